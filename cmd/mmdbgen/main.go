@@ -0,0 +1,46 @@
+// Copyright 2025 Aleksandr Zaitsev <me@axv.email>.
+// All rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the LICENSE file.
+
+// Package main implements a generator of synthetic mmdb files for tests and local runs.
+//
+// Without input file it writes the default City fixture, otherwise networks are read
+// from JSON array of objects like {"cidr": "10.0.0.0/8", "data": {"country": {"iso_code": "SE"}}}.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func main() {
+	output := flag.String("output", mmdbtest.DBName, "output mmdb file")
+	input := flag.String("input", "", "JSON file with networks, default City fixture is used if empty")
+	databaseType := flag.String("type", mmdbtest.CityType, "database type")
+	flag.Parse()
+
+	networks := mmdbtest.CityNetworks(mmdbtest.Cities)
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		networks, err = mmdbtest.DecodeNetworks(f)
+		if closeErr := f.Close(); closeErr != nil {
+			log.Printf("close %q: %v", *input, closeErr)
+		}
+
+		if err != nil {
+			log.Fatalf("parse %q: %v", *input, err)
+		}
+	}
+
+	if err := mmdbtest.WriteFile(*output, *databaseType, networks); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("written %d networks to %q", len(networks), *output)
+}
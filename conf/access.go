@@ -0,0 +1,36 @@
+package conf
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// AccessFormats are response formats of denied requests.
+var AccessFormats = []string{"text", "json", "xml", "html"} //nolint:gochecknoglobals
+
+// Access is a geo-fencing policy for the service itself.
+// Denied requests get Status code (403 by default) and a custom body of Responses by format.
+type Access struct {
+	Responses map[string]string `json:"responses"`
+	Policy
+	Status int `json:"status"`
+}
+
+// compile checks settings and prepares the policy.
+func (a *Access) compile() error {
+	if a.Status == 0 {
+		a.Status = http.StatusForbidden
+	}
+
+	if a.Status < http.StatusBadRequest || a.Status > 599 {
+		return fmt.Errorf("status %d is not a client or server error", a.Status)
+	}
+
+	for format := range a.Responses {
+		if !slices.Contains(AccessFormats, format) {
+			return fmt.Errorf("unknown response format %q", format)
+		}
+	}
+	return a.Policy.compile()
+}
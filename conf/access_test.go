@@ -0,0 +1,41 @@
+package conf
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAccess_compile(t *testing.T) {
+	cases := []struct {
+		name   string
+		access Access
+		status int
+		err    bool
+	}{
+		{name: "empty", status: http.StatusForbidden},
+		{name: "status", access: Access{Status: http.StatusUnavailableForLegalReasons}, status: 451},
+		{name: "responses", access: Access{Responses: map[string]string{"json": "{}", "html": "<p>denied</p>"}}, status: 403},
+		{name: "success status", access: Access{Status: http.StatusOK}, err: true},
+		{name: "unknown status", access: Access{Status: 600}, err: true},
+		{name: "unknown format", access: Access{Responses: map[string]string{"yaml": "denied"}}, err: true},
+		{name: "bad policy", access: Access{Policy: Policy{Allow: Matcher{CIDRs: []string{"bad"}}}}, err: true},
+	}
+	for _, c := range cases {
+		err := c.access.compile()
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected error", c.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		if c.access.Status != c.status {
+			t.Errorf("%s: not equal status %d != %d", c.name, c.access.Status, c.status)
+		}
+	}
+}
@@ -9,32 +9,59 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
-	"github.com/oschwald/geoip2-golang"
-)
 
-const defaultISOCode = "en"
+	"github.com/z0rr0/ipinfo/geo"
+)
 
 // Cfg is configuration settings struct.
+// Redirects and Templates are initial ones, they can be reloaded later,
+// so Redirect and Template methods should be used to get actual values.
 type Cfg struct {
 	ignoredHeaders map[string]struct{}
-	storage        *geoip2.Reader
-	cache          *lru.Cache[string, *geoip2.City]
-	Host           string   `json:"host"`
-	Db             string   `json:"db"`
-	IPHeader       string   `json:"ip_header"`
-	IgnoreHeaders  []string `json:"ignore_headers"`
-	Port           uint     `json:"port"`
-	CacheSize      int      `json:"cache_size"`
+	storage        geo.Locator
+	cache          *lru.Cache[netip.Addr, *geo.Record]
+	redirects      atomic.Pointer[map[string]*Redirect]
+	templates      atomic.Pointer[map[string]*Template]
+	reserved       func(path string) bool
+	filename       string
+	Redirects      map[string]*Redirect `json:"redirects"`
+	Templates      []*Template          `json:"templates"`
+	Host           string               `json:"host"`
+	Db             string               `json:"db"`
+	ASNDb          string               `json:"asn_db"`
+	IPHeader       string               `json:"ip_header"`
+	CSV            CSVConfig            `json:"csv"`
+	Databases      []string             `json:"databases"`
+	IgnoreHeaders  []string             `json:"ignore_headers"`
+	Vendors        []geo.Vendor         `json:"vendors"`
+	Validation     geo.Validation       `json:"validation"`
+	PoPs           []PoP                `json:"pops"`
+	Auth           Policy               `json:"auth"`
+	UserAgents     UserAgents           `json:"user_agents"`
+	Access         Access               `json:"access"`
+	Port           uint                 `json:"port"`
+	ProxyHops      uint                 `json:"proxy_hops"`
+	CacheSize      int                  `json:"cache_size"`
+	Preload        bool                 `json:"preload"`
+}
+
+// CSVConfig is GeoLite2 City CSV files settings, they are used instead of Db if blocks are set.
+type CSVConfig struct {
+	Blocks    []string `json:"blocks"`
+	Locations []string `json:"locations"`
 }
 
 // StrParam is common struct for headers and form params.
@@ -44,21 +71,32 @@ type StrParam struct {
 }
 
 // IPInfo is IP and related info for response.
+// SubdivisionCode is ISO 3166-2 code, e.g. "SE-M".
 type IPInfo struct {
-	Timestamp time.Time `json:"-"         xml:"-"`
-	IP        string    `json:"ip"        xml:"ip"`
-	Country   string    `json:"country"   xml:"country"`
-	City      string    `json:"city"      xml:"city"`
-	UTCTime   string    `json:"utc_time"  xml:"utc_time"`
-	TimeZone  string    `json:"time_zone" xml:"time_zone"`
-	Language  string    `json:"language"  xml:"language"`
-	Longitude float64   `json:"longitude" xml:"longitude"`
-	Latitude  float64   `json:"latitude"  xml:"latitude"`
+	Timestamp       time.Time    `json:"-"                         xml:"-"                         yaml:"-"`
+	Addr            netip.Addr   `json:"-"                         xml:"-"                         yaml:"-"`
+	IP              string       `json:"ip"                        xml:"ip"                        yaml:"ip"`
+	Country         string       `json:"country"                   xml:"country"                   yaml:"country"`
+	CountryCode     string       `json:"country_code"              xml:"country_code"              yaml:"country_code"`
+	Continent       string       `json:"continent"                 xml:"continent"                 yaml:"continent"`
+	Subdivision     string       `json:"subdivision"               xml:"subdivision"               yaml:"subdivision"`
+	SubdivisionCode string       `json:"subdivision_code"          xml:"subdivision_code"          yaml:"subdivision_code"`
+	City            string       `json:"city"                      xml:"city"                      yaml:"city"`
+	ASOrganization  string       `json:"as_organization,omitempty" xml:"as_organization,omitempty" yaml:"as_organization,omitempty"`
+	UTCTime         string       `json:"utc_time"                  xml:"utc_time"                  yaml:"utc_time"`
+	TimeZone        string       `json:"time_zone"                 xml:"time_zone"                 yaml:"time_zone"`
+	Language        string       `json:"language"                  xml:"language"                  yaml:"language"`
+	Sources         *geo.Sources `json:"sources,omitempty"         xml:"sources,omitempty"         yaml:"sources,omitempty"`
+	TimeZoneInfo    `yaml:",inline"`
+	Longitude       float64 `json:"longitude"                 xml:"longitude"                 yaml:"longitude"`
+	Latitude        float64 `json:"latitude"                  xml:"latitude"                  yaml:"latitude"`
+	ASN             uint32  `json:"asn,omitempty"             xml:"asn,omitempty"             yaml:"asn,omitempty"`
+	AccuracyRadius  uint16  `json:"accuracy_radius"           xml:"accuracy_radius"           yaml:"accuracy_radius"`
 }
 
 // LocalTime returns local time in RFC3339 format or "-" if error.
 func (i *IPInfo) LocalTime() string {
-	loc, err := time.LoadLocation(i.TimeZone)
+	loc, err := LoadLocation(i.TimeZone)
 	if err != nil {
 		return "-"
 	}
@@ -67,7 +105,7 @@ func (i *IPInfo) LocalTime() string {
 
 // LocalDateTime returns separated local date and time strings or "-" if error.
 func (i *IPInfo) LocalDateTime() (string, string) {
-	loc, err := time.LoadLocation(i.TimeZone)
+	loc, err := LoadLocation(i.TimeZone)
 	if err != nil {
 		return "-", "-"
 	}
@@ -76,6 +114,11 @@ func (i *IPInfo) LocalDateTime() (string, string) {
 	return locTime.Format(time.DateOnly), locTime.Format(time.TimeOnly)
 }
 
+// HasCoordinates returns true if the client's coordinates are known.
+func (i *IPInfo) HasCoordinates() bool {
+	return i.Latitude != 0 || i.Longitude != 0
+}
+
 // Location returns location string.
 func (i *IPInfo) Location() string {
 	if i.Country == "" {
@@ -93,38 +136,77 @@ func (c *Cfg) Info(r *http.Request) (*IPInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	return c.LookupInfo(host)
+}
 
-	city, err := c.GetCity(host)
+// LookupInfo returns info about IP address, it's used for the client and batch lookups.
+func (c *Cfg) LookupInfo(host string) (*IPInfo, error) {
+	addr, err := netip.ParseAddr(host)
 	if err != nil {
 		return nil, err
 	}
 
-	isoCode := strings.ToLower(city.Country.IsoCode)
-	if _, ok := city.Country.Names[isoCode]; !ok {
-		isoCode = defaultISOCode
+	record, err := c.Lookup(addr)
+	if err != nil {
+		return nil, err
 	}
 
+	isoCode := record.Language()
+	country, _ := record.Country.Names.Get(isoCode)
+	subdivision, _ := record.Subdivision.Names.Get(isoCode)
+	city, _ := record.City.Names.Get(isoCode)
+
 	utcNow := time.Now().UTC()
 	info := IPInfo{
-		IP:        host,
-		Country:   city.Country.Names[isoCode],
-		City:      city.City.Names[isoCode],
-		Longitude: city.Location.Longitude,
-		Latitude:  city.Location.Latitude,
-		UTCTime:   utcNow.Format(time.RFC3339),
-		TimeZone:  city.Location.TimeZone,
-		Language:  isoCode,
-		Timestamp: utcNow,
+		Addr:            addr.Unmap(),
+		IP:              host,
+		Country:         country,
+		CountryCode:     record.Country.ISOCode,
+		Continent:       record.Continent.Code,
+		Subdivision:     subdivision,
+		SubdivisionCode: subdivisionCode(record),
+		City:            city,
+		ASOrganization:  record.ASOrganization,
+		ASN:             record.ASN,
+		Longitude:       record.Location.Longitude,
+		Latitude:        record.Location.Latitude,
+		UTCTime:         utcNow.Format(time.RFC3339),
+		TimeZone:        record.Location.TimeZone,
+		Language:        isoCode,
+		Timestamp:       utcNow,
+		AccuracyRadius:  record.Location.AccuracyRadius,
+	}
+
+	// empty time zone is loaded as UTC, but it's unknown
+	if loc, locErr := LoadLocation(info.TimeZone); info.TimeZone != "" && locErr == nil {
+		info.TimeZoneInfo = NewTimeZoneInfo(utcNow, loc)
+	}
+
+	if record.Sources != (geo.Sources{}) {
+		sources := record.Sources
+		info.Sources = &sources
 	}
 	return &info, nil
 }
 
+// subdivisionCode returns ISO 3166-2 code of the record subdivision.
+func subdivisionCode(record *geo.Record) string {
+	if record.Country.ISOCode == "" || record.Subdivision.ISOCode == "" {
+		return ""
+	}
+	return record.Country.ISOCode + "-" + record.Subdivision.ISOCode
+}
+
 // Addr returns service's net address.
 func (c *Cfg) Addr() string {
 	return net.JoinHostPort(c.Host, strconv.FormatUint(uint64(c.Port), 10))
 }
 
 // GetIP return string IP address.
+// If the header is a list of addresses like X-Forwarded-For, every trusted proxy appends
+// its client to the end, so the address added by the first of ProxyHops proxies is returned.
+// Addresses before it are set by the client and can be spoofed. Empty items are skipped,
+// and it's an error if there are fewer addresses than proxies.
 func (c *Cfg) GetIP(r *http.Request) (string, error) {
 	if c.IPHeader == "" {
 		host, _, err := net.SplitHostPort(r.RemoteAddr)
@@ -133,28 +215,52 @@ func (c *Cfg) GetIP(r *http.Request) (string, error) {
 		}
 		return host, nil
 	}
-	if values, ok := r.Header[c.IPHeader]; ok {
-		return values[0], nil
+	values, ok := r.Header[c.IPHeader]
+	if !ok {
+		return "", errors.New("no real ip header")
+	}
+
+	var addresses []string
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				addresses = append(addresses, item)
+			}
+		}
+	}
+
+	hops := int(max(c.ProxyHops, 1))
+	switch {
+	case len(addresses) == 0:
+		return "", fmt.Errorf("no addresses in %s header", c.IPHeader)
+	case len(addresses) < hops:
+		return "", fmt.Errorf("%s header has %d addresses, expected at least %d", c.IPHeader, len(addresses), hops)
 	}
-	return "", errors.New("no real ip header")
+	return addresses[len(addresses)-hops], nil
 }
 
-// GetCity returns city info found by IP address.
-func (c *Cfg) GetCity(host string) (*geoip2.City, error) {
+// Lookup returns compact geo record found by IP address.
+// Unknown addresses have an empty record, it's cached too.
+func (c *Cfg) Lookup(addr netip.Addr) (*geo.Record, error) {
+	addr = addr.Unmap().WithZone("")
 	if c.cache != nil {
-		if city, ok := c.cache.Get(host); ok {
-			return city, nil
+		if record, ok := c.cache.Get(addr); ok {
+			return record, nil
 		}
 	}
-	ip := net.ParseIP(host)
-	city, err := c.storage.City(ip)
+
+	record, err := c.storage.Lookup(addr)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, geo.ErrNotFound) {
+			return nil, err
+		}
+		record = &geo.Record{}
 	}
+
 	if c.cache != nil {
-		c.cache.Add(host, city)
+		c.cache.Add(addr, record)
 	}
-	return city, nil
+	return record, nil
 }
 
 // Close closes db storage file.
@@ -165,39 +271,113 @@ func (c *Cfg) Close() error {
 	return nil
 }
 
-// New returns new rates configuration.
+// New returns new configuration, geo locator is opened by Db path.
 func New(filename string) (*Cfg, error) {
 	jsonData, err := readConfig(filename)
 	if err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
-	c := &Cfg{}
+	c := &Cfg{filename: filename}
 	err = json.Unmarshal(jsonData, c)
 	if err != nil {
 		return nil, err
 	}
 
-	c.ignoredHeaders = make(map[string]struct{})
-	for _, h := range c.IgnoreHeaders {
-		c.ignoredHeaders[strings.ToUpper(h)] = struct{}{}
+	if err = c.setDynamic(c.Redirects, c.Templates); err != nil {
+		return nil, err
 	}
 
-	// db storage
-	storage, err := geoip2.Open(c.Db)
-	if err != nil {
+	if err = c.Auth.compile(); err != nil {
+		return nil, fmt.Errorf("auth policy: %w", err)
+	}
+
+	if err = c.Access.compile(); err != nil {
+		return nil, fmt.Errorf("access policy: %w", err)
+	}
+
+	if err = c.UserAgents.compile(); err != nil {
+		return nil, fmt.Errorf("user agents: %w", err)
+	}
+
+	for i := range c.Vendors {
+		if err = c.Vendors[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = validatePoPs(c.PoPs); err != nil {
 		return nil, err
 	}
-	c.storage = storage
 
-	err = c.setCache()
+	storage, err := c.openStorage()
 	if err != nil {
-		return nil, fmt.Errorf("set cache: %w", err)
+		return nil, fmt.Errorf("open storage: %w", err)
+	}
+
+	if err = c.Validation.Validate(storage, time.Now()); err != nil {
+		if !c.Validation.WarnOnly {
+			return nil, errors.Join(fmt.Errorf("validate storage: %w", err), storage.Close())
+		}
+		slog.Warn("storage validation", "error", err)
+	}
+
+	if err = c.Setup(storage); err != nil {
+		return nil, errors.Join(err, storage.Close())
 	}
 
 	return c, nil
 }
 
+// Reload reads the configuration file again and replaces redirect rules and templates.
+// Other settings are not changed, they require restart.
+func (c *Cfg) Reload() error {
+	jsonData, err := readConfig(c.filename)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	var reloaded struct {
+		Redirects map[string]*Redirect `json:"redirects"`
+		Templates []*Template          `json:"templates"`
+	}
+	if err = json.Unmarshal(jsonData, &reloaded); err != nil {
+		return err
+	}
+	return c.setDynamic(reloaded.Redirects, reloaded.Templates)
+}
+
+// setDynamic checks and sets settings which can be reloaded, they are replaced only if all of them are valid.
+func (c *Cfg) setDynamic(redirects map[string]*Redirect, templates []*Template) error {
+	if err := compileRedirects(redirects); err != nil {
+		return err
+	}
+
+	loaded, err := loadTemplates(templates, c.reserved)
+	if err != nil {
+		return err
+	}
+
+	c.redirects.Store(&redirects)
+	c.templates.Store(&loaded)
+	return nil
+}
+
+// Setup initializes internal fields and sets geo locator, the configuration owns it after the call.
+// It's called by New, but can be used directly with custom locators, e.g. in tests.
+func (c *Cfg) Setup(storage geo.Locator) error {
+	c.ignoredHeaders = make(map[string]struct{})
+	for _, h := range c.IgnoreHeaders {
+		c.ignoredHeaders[strings.ToUpper(h)] = struct{}{}
+	}
+
+	c.storage = storage
+	if err := c.setCache(); err != nil {
+		return fmt.Errorf("set cache: %w", err)
+	}
+	return nil
+}
+
 // GetHeaders returns sorted request headers excluding ignored values.
 func (c *Cfg) GetHeaders(r *http.Request) []StrParam {
 	result := make([]StrParam, 0, len(r.Header))
@@ -226,12 +406,71 @@ func (c *Cfg) GetParams(r *http.Request) []StrParam {
 	return result
 }
 
+// StorageInfo returns a short description of geo storage.
+func (c *Cfg) StorageInfo() string {
+	if s, ok := c.storage.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", c.storage)
+}
+
+// BuildTime returns the oldest build time of geo databases or zero time if it's unknown.
+func (c *Cfg) BuildTime() time.Time {
+	return geo.BuildTime(c.storage)
+}
+
+// openStorage opens geo locator, ASN database is joined to it if it's set.
+func (c *Cfg) openStorage() (geo.Locator, error) {
+	locator, err := c.openLocator()
+	if err != nil || c.ASNDb == "" {
+		return locator, err
+	}
+
+	asn, err := c.openMMDB(c.ASNDb)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("asn database: %w", err), locator.Close())
+	}
+	return &geo.ASNJoin{Locator: locator, ASN: asn}, nil
+}
+
+func (c *Cfg) openLocator() (geo.Locator, error) {
+	switch {
+	case len(c.CSV.Blocks) > 0:
+		return geo.LoadCSV(c.CSV.Blocks, c.CSV.Locations)
+	case len(c.Databases) > 0:
+		return c.openDatabases()
+	}
+	return c.openMMDB(c.Db)
+}
+
+// openMMDB opens a database file using memory map or loads it into memory if preload is set.
+func (c *Cfg) openMMDB(filename string) (*geo.MMDB, error) {
+	if c.Preload {
+		return geo.LoadMMDB(filename, c.Vendors...)
+	}
+	return geo.OpenMMDB(filename, c.Vendors...)
+}
+
+// openDatabases opens all databases as merged sources named by file names.
+func (c *Cfg) openDatabases() (geo.Locator, error) {
+	merge := make(geo.Merge, 0, len(c.Databases))
+
+	for _, filename := range c.Databases {
+		db, err := c.openMMDB(filename)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("database %q: %w", filename, err), merge.Close())
+		}
+		merge = append(merge, geo.Source{Locator: db, Name: filepath.Base(filename)})
+	}
+	return merge, nil
+}
+
 func (c *Cfg) setCache() error {
 	if c.CacheSize <= 0 {
 		return nil
 	}
 
-	cache, err := lru.New[string, *geoip2.City](c.CacheSize)
+	cache, err := lru.New[netip.Addr, *geo.Record](c.CacheSize)
 	if err != nil {
 		return err
 	}
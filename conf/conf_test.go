@@ -6,12 +6,34 @@
 package conf
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net"
 	"net/http/httptest"
+	"net/netip"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/z0rr0/ipinfo/geo"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+	"github.com/z0rr0/ipinfo/internal/testenv"
+)
+
+const (
+	testConfigName = mmdbtest.ConfigName
+	benchIP        = "193.138.218.226"
 )
 
-const testConfigName = "/tmp/ipinfo_test.json"
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
+}
 
 func TestNew(t *testing.T) {
 	if _, err := New("/bad_file_path.json"); err == nil {
@@ -27,18 +49,48 @@ func TestNew(t *testing.T) {
 		t.Error("empty address")
 	}
 
+	if info := cfg.StorageInfo(); !strings.HasPrefix(info, "mmdb: ") || !strings.Contains(info, "; asn mmdb: ") {
+		t.Errorf("unexpected storage info: %v", info)
+	}
+
 	if err = cfg.Close(); err != nil {
 		t.Errorf("close error: %v", err)
 	}
 
+	const configName = "bad_asn.json"
+	config := `{"db": "` + mmdbtest.DBName + `", "asn_db": "bad.mmdb"}`
+	if err = os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = New(configName); err == nil {
+		t.Error("expected error for bad ASN database")
+	}
+
 	cfg.storage = nil
 	if err = cfg.Close(); err != nil {
 		t.Errorf("close error with empty storage: %v", err)
 	}
 }
 
-func TestCfg_GetCity(t *testing.T) {
-	cfg, err := New(testConfigName)
+func TestNew_databases(t *testing.T) {
+	const (
+		configName    = "databases.json"
+		countriesName = "countries.mmdb"
+	)
+	networks := []mmdbtest.Network{
+		{CIDR: "10.0.0.0/8", Data: map[string]any{"country": map[string]any{"iso_code": "NO", "names": map[string]any{"en": "Norway"}}}},
+	}
+	if err := mmdbtest.WriteFile(countriesName, "GeoLite2-Country", networks); err != nil {
+		t.Fatal(err)
+	}
+
+	config := `{"ip_header": "X-Real-Ip", "databases": ["` + mmdbtest.DBName + `", "` + countriesName + `"]}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := New(configName)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,20 +100,253 @@ func TestCfg_GetCity(t *testing.T) {
 		}
 	}()
 
-	_, err = cfg.GetCity("127.0.0.1")
+	if info := cfg.StorageInfo(); !strings.HasPrefix(info, "merge: "+mmdbtest.DBName+" (mmdb: ") {
+		t.Errorf("unexpected storage info: %v", info)
+	}
+
+	cases := []struct {
+		ip      string
+		country string
+		city    string
+		sources geo.Sources
+	}{
+		{
+			ip:      "193.138.218.226",
+			country: "Sweden",
+			city:    "Malmo",
+			sources: geo.Sources{Country: mmdbtest.DBName, City: mmdbtest.DBName, Location: mmdbtest.DBName},
+		},
+		{ip: "10.1.2.3", country: "Norway", sources: geo.Sources{Country: countriesName}},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/foo", nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Errorf("%s: info error: %v", c.ip, infoErr)
+			continue
+		}
+
+		if info.Country != c.country || info.City != c.city {
+			t.Errorf("%s: unexpected location %q", c.ip, info.Location())
+		}
+
+		if info.Sources == nil || *info.Sources != c.sources {
+			t.Errorf("%s: not equal sources %v != %v", c.ip, info.Sources, c.sources)
+		}
+	}
+
+	if err = os.WriteFile(configName, []byte(`{"databases": ["bad.mmdb"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = New(configName); err == nil {
+		t.Error("expected error for bad database")
+	}
+}
+
+// writeCSVFixture writes GeoLite2 City CSV files of the cities and returns names of blocks and locations files.
+// Geoname IDs are indexes of cities, a locations file is written for every locale of their names.
+func writeCSVFixture(t *testing.T, cities []mmdbtest.City) (string, []string) {
+	const blocksName = "blocks.csv"
+	files := map[string][][]string{
+		blocksName: {{"network", "geoname_id", "latitude", "longitude", "accuracy_radius"}},
+	}
+
+	for i := range cities {
+		c := &cities[i]
+		files[blocksName] = append(files[blocksName], []string{
+			c.CIDR,
+			strconv.Itoa(i),
+			strconv.FormatFloat(c.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(c.Longitude, 'f', -1, 64),
+			strconv.FormatUint(uint64(c.AccuracyRadius), 10),
+		})
+	}
+
+	var locations []string
+	for i := range cities {
+		for _, names := range []map[string]string{cities[i].Country, cities[i].City, cities[i].Subdivision} {
+			for locale := range names {
+				name := "locations-" + locale + ".csv"
+				if _, ok := files[name]; ok {
+					continue
+				}
+
+				rows := [][]string{{
+					"geoname_id", "locale_code", "continent_code", "country_iso_code", "country_name",
+					"subdivision_1_iso_code", "subdivision_1_name", "city_name", "time_zone",
+				}}
+				for j := range cities {
+					c := &cities[j]
+					rows = append(rows, []string{
+						strconv.Itoa(j), locale, c.Continent, c.ISOCode, c.Country[locale],
+						c.SubdivisionCode, c.Subdivision[locale], c.City[locale], c.TimeZone,
+					})
+				}
+
+				files[name] = rows
+				locations = append(locations, name)
+			}
+		}
+	}
+
+	for name, rows := range files {
+		var buf bytes.Buffer
+		if err := csv.NewWriter(&buf).WriteAll(rows); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return blocksName, locations
+}
+
+func TestNew_csv(t *testing.T) {
+	const (
+		csvConfigName  = "csv.json"
+		mmdbConfigName = "mmdb.json"
+	)
+	blocks, locations := writeCSVFixture(t, mmdbtest.Cities)
+
+	locationsJSON, err := json.Marshal(locations)
 	if err != nil {
-		t.Errorf("get city error: %v", err)
+		t.Fatal(err)
 	}
 
-	// read from cache
-	_, err = cfg.GetCity("127.0.0.1")
+	// both sources have the same City data of the fixture
+	configs := map[string]*Cfg{csvConfigName: nil, mmdbConfigName: nil}
+	contents := map[string]string{
+		csvConfigName:  `{"csv": {"blocks": ["` + blocks + `"], "locations": ` + string(locationsJSON) + `}}`,
+		mmdbConfigName: `{"db": "` + mmdbtest.DBName + `"}`,
+	}
+	for name := range configs {
+		if err = os.WriteFile(name, []byte(contents[name]), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, cfgErr := New(name)
+		if cfgErr != nil {
+			t.Fatalf("%s: %v", name, cfgErr)
+		}
+		defer func() {
+			if closeErr := cfg.Close(); closeErr != nil {
+				t.Errorf("%s: close error: %v", name, closeErr)
+			}
+		}()
+		configs[name] = cfg
+	}
+
+	if info := configs[csvConfigName].StorageInfo(); !strings.HasPrefix(info, "csv: ") {
+		t.Errorf("unexpected storage info: %v", info)
+	}
+
+	ips := []string{"127.0.0.1", "2001:db8::1", "::ffff:193.138.218.226"}
+	for _, city := range mmdbtest.Cities {
+		ips = append(ips, netip.MustParsePrefix(city.CIDR).Addr().Next().String())
+	}
+
+	for _, ip := range ips {
+		expected, expectedErr := configs[mmdbConfigName].LookupInfo(ip)
+		if expectedErr != nil {
+			t.Fatalf("%s: mmdb lookup error: %v", ip, expectedErr)
+		}
+
+		info, infoErr := configs[csvConfigName].LookupInfo(ip)
+		if infoErr != nil {
+			t.Fatalf("%s: csv lookup error: %v", ip, infoErr)
+		}
+
+		// only lookup time can differ
+		info.Timestamp, info.UTCTime = expected.Timestamp, expected.UTCTime
+		if !reflect.DeepEqual(info, expected) {
+			t.Errorf("%s: not equal\n%+v\n%+v", ip, *info, *expected)
+		}
+	}
+}
+
+func TestNew_validation(t *testing.T) {
+	const configName = "validation.json"
+	cases := []struct {
+		name       string
+		validation string
+		fail       bool
+	}{
+		{name: "valid", validation: `{"canaries": {"193.138.218.226": "SE"}, "max_age_days": 7, "verify": true}`},
+		{name: "invalid", validation: `{"canaries": {"193.138.218.226": "NO"}}`, fail: true},
+		{name: "warning", validation: `{"canaries": {"193.138.218.226": "NO"}, "warn_only": true}`},
+	}
+	for _, c := range cases {
+		config := `{"db": "` + mmdbtest.DBName + `", "validation": ` + c.validation + `}`
+		if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := New(configName)
+		if c.fail {
+			if err == nil || !strings.Contains(err.Error(), `expected country "NO", got "SE"`) {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		if err = cfg.Close(); err != nil {
+			t.Errorf("%s: close error: %v", c.name, err)
+		}
+	}
+}
+
+func TestCfg_Lookup(t *testing.T) {
+	cfg, err := New(testConfigName)
 	if err != nil {
-		t.Errorf("get city error: %v", err)
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	addr := netip.MustParseAddr("127.0.0.1")
+	if _, err = cfg.Lookup(addr); err != nil {
+		t.Errorf("lookup error: %v", err)
 	}
 
-	if _, ok := cfg.cache.Get("127.0.0.1"); !ok {
+	// read from cache
+	if _, err = cfg.Lookup(addr); err != nil {
+		t.Errorf("lookup error: %v", err)
+	}
+
+	if _, ok := cfg.cache.Get(addr); !ok {
 		t.Error("cache miss")
 	}
+
+	// IPv4-mapped address shares cache item with IPv4 one
+	record, err := cfg.Lookup(netip.MustParseAddr("::ffff:193.138.218.226"))
+	if err != nil {
+		t.Fatalf("lookup error: %v", err)
+	}
+
+	if _, ok := cfg.cache.Get(netip.MustParseAddr("193.138.218.226")); !ok {
+		t.Error("cache miss for unmapped address")
+	}
+
+	if record.City.Names.EN != "Malmo" || record.Country.Names.EN != "Sweden" || record.Country.ISOCode != "SE" {
+		t.Errorf("unexpected names: %v", *record)
+	}
+
+	expected := geo.LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 55.6078, Longitude: 12.9982, AccuracyRadius: 20}
+	if record.Location != expected {
+		t.Errorf("not equal %v != %v", record.Location, expected)
+	}
 }
 
 func TestCfg_GetIP(t *testing.T) {
@@ -81,6 +366,7 @@ func TestCfg_GetIP(t *testing.T) {
 		remoteAddr string
 		ipAddress  string
 		ipValues   []string
+		proxyHops  uint
 	}{
 		{
 			name:     "has header, no values",
@@ -101,11 +387,54 @@ func TestCfg_GetIP(t *testing.T) {
 			ipValues:  []string{"127.0.0.123"},
 			ipAddress: "127.0.0.123",
 		},
+		{
+			name:      "spoofed first hop",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"1.2.3.4, 203.0.113.7"},
+			ipAddress: "203.0.113.7",
+		},
+		{
+			name:      "forwarded addresses",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"203.0.113.7, 10.0.0.1", "10.0.0.2"},
+			ipAddress: "10.0.0.2",
+		},
+		{
+			name:      "several proxies",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"1.2.3.4, 203.0.113.7, 10.0.0.1", "10.0.0.2"},
+			proxyHops: 3,
+			ipAddress: "203.0.113.7",
+		},
+		{
+			name:      "less addresses than proxies",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"203.0.113.7"},
+			proxyHops: 2,
+		},
+		{
+			name:      "empty items",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"1.2.3.4, 203.0.113.7,", " ,"},
+			ipAddress: "203.0.113.7",
+		},
+		{
+			name:     "only empty items",
+			ipHeader: "X-Forwarded-For",
+			ipValues: []string{" , ", ""},
+		},
+		{
+			name:      "less addresses than proxies without empty items",
+			ipHeader:  "X-Forwarded-For",
+			ipValues:  []string{"203.0.113.7,", "10.0.0.2"},
+			proxyHops: 3,
+		},
 	}
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	for _, c := range cases {
 		cfg.IPHeader = c.ipHeader
+		cfg.ProxyHops = c.proxyHops
 		if len(c.ipValues) > 0 {
 			req.Header[c.ipHeader] = c.ipValues
 		}
@@ -226,22 +555,45 @@ func TestCfg_Info(t *testing.T) {
 		t.Fatalf("info error: %v", err)
 	}
 
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	expected := IPInfo{
-		IP:        "193.138.218.226",
-		Country:   "Sweden",
-		City:      "Malmo",
-		Longitude: 12.9982,
-		Latitude:  55.6078,
-		TimeZone:  "Europe/Stockholm",
-		Language:  defaultISOCode,
+		Addr:            netip.MustParseAddr("193.138.218.226"),
+		IP:              "193.138.218.226",
+		Country:         "Sweden",
+		CountryCode:     "SE",
+		Continent:       "EU",
+		Subdivision:     "Skane County",
+		SubdivisionCode: "SE-M",
+		ASOrganization:  "31173 Services AB",
+		ASN:             39351,
+		City:            "Malmo",
+		Longitude:       12.9982,
+		Latitude:        55.6078,
+		TimeZone:        "Europe/Stockholm",
+		Language:        geo.DefaultLanguage,
+		AccuracyRadius:  20,
 		// don't check time fields
-		UTCTime:   info.UTCTime,
-		Timestamp: info.Timestamp,
+		UTCTime:      info.UTCTime,
+		Timestamp:    info.Timestamp,
+		TimeZoneInfo: NewTimeZoneInfo(info.Timestamp, stockholm),
 	}
 
 	if i := *info; i != expected {
 		t.Errorf("not equal %v != %v", i, expected)
 	}
+
+	// unknown address has no time zone details
+	if info, err = cfg.LookupInfo("127.0.0.1"); err != nil {
+		t.Fatalf("info error: %v", err)
+	}
+
+	if info.TimeZone != "" || info.TimeZoneInfo != (TimeZoneInfo{}) {
+		t.Errorf("unexpected time zone %q %+v", info.TimeZone, info.TimeZoneInfo)
+	}
 }
 
 func TestIPInfo_LocalTime(t *testing.T) {
@@ -284,3 +636,79 @@ func TestIPInfo_Location(t *testing.T) {
 		}
 	}
 }
+
+// BenchmarkGeoIP2City is a previous lookup path: geoip2 reader decodes full city record.
+func BenchmarkGeoIP2City(b *testing.B) {
+	cfg, err := New(testConfigName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	reader, err := geoip2.Open(cfg.Db)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err = reader.City(net.ParseIP(benchIP)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCfg_Lookup(b *testing.B) {
+	cfg, err := New(testConfigName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cfg.cache = nil // measure decoding only
+	addr := netip.MustParseAddr(benchIP)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err = cfg.Lookup(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCfg_LookupCached(b *testing.B) {
+	cfg, err := New(testConfigName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	if cfg.cache == nil {
+		b.Skip("cache is disabled")
+	}
+	addr := netip.MustParseAddr(benchIP)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err = cfg.Lookup(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
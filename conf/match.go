@@ -0,0 +1,95 @@
+package conf
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Matcher is a set of client conditions, it matches if any of them is true.
+// Countries and continents are ISO codes, subdivisions are ISO 3166-2 codes (e.g. "US-CA"),
+// CIDRs are networks or single IP addresses.
+type Matcher struct {
+	prefixes     []netip.Prefix
+	Countries    []string `json:"countries"`
+	Continents   []string `json:"continents"`
+	Subdivisions []string `json:"subdivisions"`
+	CIDRs        []string `json:"cidrs"`
+	ASNs         []uint32 `json:"asns"`
+}
+
+// IsEmpty returns true if the matcher has no conditions.
+func (m *Matcher) IsEmpty() bool {
+	return len(m.Countries) == 0 && len(m.Continents) == 0 && len(m.Subdivisions) == 0 &&
+		len(m.CIDRs) == 0 && len(m.ASNs) == 0
+}
+
+// Match returns true if the client's info matches any condition.
+func (m *Matcher) Match(info *IPInfo) bool {
+	if containsCode(m.Countries, info.CountryCode) ||
+		containsCode(m.Continents, info.Continent) ||
+		containsCode(m.Subdivisions, info.SubdivisionCode) {
+		return true
+	}
+
+	if info.ASN != 0 && slices.Contains(m.ASNs, info.ASN) {
+		return true
+	}
+
+	if !info.Addr.IsValid() {
+		return false
+	}
+
+	addr := info.Addr.Unmap()
+	for _, prefix := range m.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile parses CIDRs, it should be called before Match.
+func (m *Matcher) compile() error {
+	m.prefixes = make([]netip.Prefix, 0, len(m.CIDRs))
+
+	for _, cidr := range m.CIDRs {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return fmt.Errorf("cidr %q: %w", cidr, err)
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	return nil
+}
+
+// parsePrefix parses a network or a single IP address as a prefix.
+func parsePrefix(value string) (netip.Prefix, error) {
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return prefix, err
+	}
+
+	if addr := prefix.Addr(); addr.Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96).Masked(), nil
+	}
+	return prefix.Masked(), nil
+}
+
+// containsCode returns true if codes contain non-empty code, the comparison is case-insensitive.
+func containsCode(codes []string, code string) bool {
+	if code == "" {
+		return false
+	}
+	return slices.ContainsFunc(codes, func(item string) bool { return strings.EqualFold(item, code) })
+}
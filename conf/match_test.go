@@ -0,0 +1,53 @@
+package conf
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	malmo := &IPInfo{
+		Addr:            netip.MustParseAddr("193.138.218.226"),
+		CountryCode:     "SE",
+		Continent:       "EU",
+		SubdivisionCode: "SE-M",
+		ASN:             39351,
+	}
+	mapped := &IPInfo{Addr: netip.MustParseAddr("::ffff:10.1.2.3")}
+
+	cases := []struct {
+		info     *IPInfo
+		name     string
+		matcher  Matcher
+		expected bool
+	}{
+		{name: "empty", info: malmo},
+		{name: "country", matcher: Matcher{Countries: []string{"NO", "se"}}, info: malmo, expected: true},
+		{name: "other country", matcher: Matcher{Countries: []string{"NO"}}, info: malmo},
+		{name: "continent", matcher: Matcher{Continents: []string{"EU"}}, info: malmo, expected: true},
+		{name: "subdivision", matcher: Matcher{Subdivisions: []string{"SE-M"}}, info: malmo, expected: true},
+		{name: "subdivision code only", matcher: Matcher{Subdivisions: []string{"M"}}, info: malmo},
+		{name: "asn", matcher: Matcher{ASNs: []uint32{13335, 39351}}, info: malmo, expected: true},
+		{name: "cidr", matcher: Matcher{CIDRs: []string{"193.138.0.0/16"}}, info: malmo, expected: true},
+		{name: "address", matcher: Matcher{CIDRs: []string{"193.138.218.226"}}, info: malmo, expected: true},
+		{name: "other cidr", matcher: Matcher{CIDRs: []string{"10.0.0.0/8"}}, info: malmo},
+		{name: "mapped address", matcher: Matcher{CIDRs: []string{"10.0.0.0/8"}}, info: mapped, expected: true},
+		{name: "mapped cidr", matcher: Matcher{CIDRs: []string{"::ffff:10.0.0.0/104"}}, info: mapped, expected: true},
+		{name: "unknown", matcher: Matcher{Countries: []string{""}, ASNs: []uint32{0}}, info: &IPInfo{}},
+	}
+	for _, c := range cases {
+		if err := c.matcher.compile(); err != nil {
+			t.Errorf("%s: compile error: %v", c.name, err)
+			continue
+		}
+
+		if m := c.matcher.Match(c.info); m != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.name, m, c.expected)
+		}
+	}
+
+	bad := Matcher{CIDRs: []string{"10.0.0.0/33"}}
+	if err := bad.compile(); err == nil {
+		t.Error("expected compile error")
+	}
+}
@@ -0,0 +1,35 @@
+package conf
+
+import "fmt"
+
+// Policy is an access policy by client's geo data.
+// Denied clients are rejected, and if allowed conditions are set, only matched clients are accepted.
+type Policy struct {
+	Allow Matcher `json:"allow"`
+	Deny  Matcher `json:"deny"`
+}
+
+// IsEmpty returns true if the policy has no conditions, so all clients are allowed.
+func (p *Policy) IsEmpty() bool {
+	return p.Allow.IsEmpty() && p.Deny.IsEmpty()
+}
+
+// Allowed returns true if the client is accepted by the policy.
+func (p *Policy) Allowed(info *IPInfo) bool {
+	if p.Deny.Match(info) {
+		return false
+	}
+	return p.Allow.IsEmpty() || p.Allow.Match(info)
+}
+
+// compile prepares the policy matchers.
+func (p *Policy) compile() error {
+	if err := p.Allow.compile(); err != nil {
+		return fmt.Errorf("allow: %w", err)
+	}
+
+	if err := p.Deny.compile(); err != nil {
+		return fmt.Errorf("deny: %w", err)
+	}
+	return nil
+}
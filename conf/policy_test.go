@@ -0,0 +1,51 @@
+package conf
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	malmo := &IPInfo{Addr: netip.MustParseAddr("193.138.218.226"), CountryCode: "SE", Continent: "EU", ASN: 39351}
+	moscow := &IPInfo{Addr: netip.MustParseAddr("5.255.255.5"), CountryCode: "RU", Continent: "EU"}
+	unknown := &IPInfo{Addr: netip.MustParseAddr("10.1.2.3")}
+
+	cases := []struct {
+		name     string
+		policy   Policy
+		expected []bool // malmo, moscow, unknown
+	}{
+		{name: "empty", expected: []bool{true, true, true}},
+		{name: "deny", policy: Policy{Deny: Matcher{Countries: []string{"RU"}}}, expected: []bool{true, false, true}},
+		{name: "allow", policy: Policy{Allow: Matcher{Continents: []string{"EU"}}}, expected: []bool{true, true, false}},
+		{
+			name: "allow and deny",
+			policy: Policy{
+				Allow: Matcher{Continents: []string{"EU"}, CIDRs: []string{"10.0.0.0/8"}},
+				Deny:  Matcher{ASNs: []uint32{39351}},
+			},
+			expected: []bool{false, true, true},
+		},
+	}
+	for _, c := range cases {
+		if err := c.policy.compile(); err != nil {
+			t.Errorf("%s: compile error: %v", c.name, err)
+			continue
+		}
+
+		if c.policy.IsEmpty() != (c.name == "empty") {
+			t.Errorf("%s: unexpected empty state", c.name)
+		}
+
+		for i, info := range []*IPInfo{malmo, moscow, unknown} {
+			if allowed := c.policy.Allowed(info); allowed != c.expected[i] {
+				t.Errorf("%s: %v not equal %v != %v", c.name, info.Addr, allowed, c.expected[i])
+			}
+		}
+	}
+
+	bad := Policy{Deny: Matcher{CIDRs: []string{"bad"}}}
+	if err := bad.compile(); err == nil {
+		t.Error("expected compile error")
+	}
+}
@@ -0,0 +1,124 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+// PoPRule is a distance weight of a point of presence for clients from some countries or continents.
+// Factor less than 1 prefers the PoP for such clients, greater than 1 deprioritizes it.
+type PoPRule struct {
+	Countries  []string `json:"countries"`
+	Continents []string `json:"continents"`
+	Factor     float64  `json:"factor"`
+}
+
+// Match returns true if the rule is applied to the client's country or continent code.
+func (r *PoPRule) Match(country, continent string) bool {
+	equal := func(code string) func(string) bool {
+		return func(item string) bool { return code != "" && strings.EqualFold(item, code) }
+	}
+	return slices.ContainsFunc(r.Countries, equal(country)) || slices.ContainsFunc(r.Continents, equal(continent))
+}
+
+// PoP is a named point of presence (server endpoint) with coordinates.
+type PoP struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Rules     []PoPRule `json:"rules"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+}
+
+// Validate checks PoP settings.
+func (p *PoP) Validate() error {
+	if p.Name == "" {
+		return errors.New("pop: empty name")
+	}
+
+	if p.URL != "" {
+		if u, err := url.Parse(p.URL); err != nil || !u.IsAbs() {
+			return fmt.Errorf("pop %q: url %q is not absolute", p.Name, p.URL)
+		}
+	}
+
+	if !(geo.Point{Latitude: p.Latitude, Longitude: p.Longitude}).Valid() {
+		return fmt.Errorf("pop %q: invalid coordinates", p.Name)
+	}
+
+	for i := range p.Rules {
+		if p.Rules[i].Factor <= 0 {
+			return fmt.Errorf("pop %q: rule %d factor should be positive", p.Name, i)
+		}
+	}
+	return nil
+}
+
+// factor returns the distance weight of the first matched rule or 1.
+func (p *PoP) factor(country, continent string) float64 {
+	for i := range p.Rules {
+		if p.Rules[i].Match(country, continent) {
+			return p.Rules[i].Factor
+		}
+	}
+	return 1
+}
+
+// RankedPoP is a point of presence with distance from the client.
+// Score is the weighted distance which is used for ranking.
+type RankedPoP struct {
+	Name      string  `json:"name"`
+	URL       string  `json:"url,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Distance  float64 `json:"distance_km"`
+	Score     float64 `json:"score"`
+}
+
+// Nearest returns points of presence ranked by weighted distance from the client.
+// If the client location is unknown, they are returned in the configured order.
+func (c *Cfg) Nearest(info *IPInfo) []RankedPoP {
+	var (
+		result  = make([]RankedPoP, len(c.PoPs))
+		client  = geo.Point{Latitude: info.Latitude, Longitude: info.Longitude}
+		located = info.HasCoordinates()
+	)
+
+	for i := range c.PoPs {
+		pop := &c.PoPs[i]
+		result[i] = RankedPoP{Name: pop.Name, URL: pop.URL, Latitude: pop.Latitude, Longitude: pop.Longitude}
+
+		if located {
+			result[i].Distance = geo.Distance(client, geo.Point{Latitude: pop.Latitude, Longitude: pop.Longitude})
+			result[i].Score = result[i].Distance * pop.factor(info.CountryCode, info.Continent)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score < result[j].Score
+	})
+	return result
+}
+
+// validatePoPs checks all points of presence and uniqueness of their names.
+func validatePoPs(pops []PoP) error {
+	names := make(map[string]struct{}, len(pops))
+
+	for i := range pops {
+		if err := pops[i].Validate(); err != nil {
+			return err
+		}
+
+		if _, ok := names[pops[i].Name]; ok {
+			return fmt.Errorf("pop %q: duplicate name", pops[i].Name)
+		}
+		names[pops[i].Name] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package conf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPoP_Validate(t *testing.T) {
+	cases := []struct {
+		name string
+		pop  PoP
+		fail bool
+	}{
+		{name: "valid", pop: PoP{Name: "eu", URL: "https://eu.example.com", Latitude: 59.33, Longitude: 18.06}},
+		{name: "no url", pop: PoP{Name: "eu"}},
+		{name: "rules", pop: PoP{Name: "eu", Rules: []PoPRule{{Countries: []string{"SE"}, Factor: 0.5}}}},
+		{name: "no name", pop: PoP{URL: "https://eu.example.com"}, fail: true},
+		{name: "relative url", pop: PoP{Name: "eu", URL: "eu.example.com"}, fail: true},
+		{name: "bad latitude", pop: PoP{Name: "eu", Latitude: 95}, fail: true},
+		{name: "bad factor", pop: PoP{Name: "eu", Rules: []PoPRule{{Continents: []string{"EU"}}}}, fail: true},
+	}
+	for _, c := range cases {
+		if err := c.pop.Validate(); (err != nil) != c.fail {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+	}
+
+	if err := validatePoPs([]PoP{{Name: "eu"}, {Name: "eu"}}); err == nil {
+		t.Error("expected duplicate name error")
+	}
+}
+
+func TestCfg_Nearest(t *testing.T) {
+	pops := []PoP{
+		{Name: "tokyo", URL: "https://jp.example.com", Latitude: 35.68, Longitude: 139.69},
+		{Name: "london", URL: "https://uk.example.com", Latitude: 51.51, Longitude: -0.13},
+		{Name: "stockholm", URL: "https://se.example.com", Latitude: 59.33, Longitude: 18.06},
+	}
+	weighted := []PoP{
+		{Name: "tokyo", Latitude: 35.68, Longitude: 139.69, Rules: []PoPRule{{Countries: []string{"se"}, Factor: 0.01}}},
+		{Name: "london", Latitude: 51.51, Longitude: -0.13, Rules: []PoPRule{{Continents: []string{"EU"}, Factor: 0.05}}},
+		pops[2],
+	}
+	malmo := &IPInfo{CountryCode: "SE", Continent: "EU", Latitude: 55.6078, Longitude: 12.9982}
+	tokyo := &IPInfo{CountryCode: "JP", Continent: "AS", Latitude: 35.69, Longitude: 139.69}
+
+	cases := []struct {
+		name     string
+		info     *IPInfo
+		pops     []PoP
+		expected []string
+	}{
+		{name: "malmo", info: malmo, pops: pops, expected: []string{"stockholm", "london", "tokyo"}},
+		{name: "tokyo", info: tokyo, pops: pops, expected: []string{"tokyo", "stockholm", "london"}},
+		{name: "weighted", info: malmo, pops: weighted, expected: []string{"london", "tokyo", "stockholm"}},
+		{name: "not matched", info: tokyo, pops: weighted, expected: []string{"tokyo", "stockholm", "london"}},
+		{name: "unknown", info: &IPInfo{}, pops: pops, expected: []string{"tokyo", "london", "stockholm"}},
+		{name: "empty", info: malmo},
+	}
+	for _, c := range cases {
+		cfg := &Cfg{PoPs: c.pops}
+		result := cfg.Nearest(c.info)
+
+		if len(result) != len(c.expected) {
+			t.Errorf("%s: unexpected length %d", c.name, len(result))
+			continue
+		}
+
+		for i, name := range c.expected {
+			if result[i].Name != name {
+				t.Errorf("%s: not equal %d name %v != %v", c.name, i, result[i].Name, name)
+			}
+		}
+	}
+
+	result := (&Cfg{PoPs: pops}).Nearest(malmo)
+	if d := result[0].Distance; math.Abs(d-512.5) > 0.1 || result[0].Score != d || result[0].URL != "https://se.example.com" {
+		t.Errorf("unexpected nearest: %v", result[0])
+	}
+}
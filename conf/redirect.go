@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// RedirectRule is a target URL for clients which match the rule.
+type RedirectRule struct {
+	Target string `json:"target"`
+	Matcher
+}
+
+// Redirect is a named set of rules, the first matched one is used or Default if nothing matches.
+type Redirect struct {
+	Default string         `json:"default"`
+	Rules   []RedirectRule `json:"rules"`
+}
+
+// Target returns URL for the client or an empty string if there is no matched rule and default value.
+func (r *Redirect) Target(info *IPInfo) string {
+	for i := range r.Rules {
+		if r.Rules[i].Match(info) {
+			return r.Rules[i].Target
+		}
+	}
+	return r.Default
+}
+
+// compile checks the targets and rules, and prepares their matchers.
+func (r *Redirect) compile() error {
+	if r.Default != "" {
+		if err := checkTarget(r.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+
+		if rule.Target == "" {
+			return fmt.Errorf("rule %d: empty target", i)
+		}
+
+		if err := checkTarget(rule.Target); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		if rule.IsEmpty() {
+			return fmt.Errorf("rule %d: no conditions", i)
+		}
+
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkTarget returns an error if the target is not absolute HTTP(S) URL.
+func checkTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("target %q is not absolute http(s) url", target)
+	}
+	return nil
+}
+
+// Redirect returns target URL of the named redirect for the client.
+// It returns false if there is no such redirect.
+func (c *Cfg) Redirect(name string, info *IPInfo) (string, bool) {
+	redirects := c.redirects.Load()
+	if redirects == nil {
+		return "", false
+	}
+
+	redirect, ok := (*redirects)[name]
+	if !ok {
+		return "", false
+	}
+	return redirect.Target(info), true
+}
+
+// compileRedirects checks and prepares redirect rules.
+func compileRedirects(redirects map[string]*Redirect) error {
+	for name, redirect := range redirects {
+		if redirect == nil {
+			return fmt.Errorf("redirect %q: empty", name)
+		}
+
+		if err := redirect.compile(); err != nil {
+			return fmt.Errorf("redirect %q: %w", name, err)
+		}
+	}
+	return nil
+}
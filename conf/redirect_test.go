@@ -0,0 +1,114 @@
+package conf
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestCfg_Redirect(t *testing.T) {
+	const configName = "redirects.json"
+	config := `{
+		"ip_header": "X-Real-Ip",
+		"db": "` + mmdbtest.DBName + `",
+		"asn_db": "` + mmdbtest.ASNDBName + `",
+		"redirects": {
+			"download": {
+				"default": "https://example.com/download",
+				"rules": [
+					{"cidrs": ["10.0.0.0/8"], "target": "https://internal.example.com/download"},
+					{"asns": [20712], "target": "https://aa.example.com/download"},
+					{"countries": ["SE"], "target": "https://se.example.com/download"},
+					{"continents": ["AS"], "target": "https://asia.example.com/download"}
+				]
+			},
+			"docs": {"rules": [{"subdivisions": ["GB-ENG"], "target": "https://en.example.com/docs"}]}
+		}
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cases := []struct {
+		name     string
+		ip       string
+		expected string
+		found    bool
+	}{
+		{name: "download", ip: "193.138.218.226", expected: "https://se.example.com/download", found: true},
+		{name: "download", ip: "81.2.69.1", expected: "https://aa.example.com/download", found: true},
+		{name: "download", ip: "2001:218::1", expected: "https://asia.example.com/download", found: true},
+		{name: "download", ip: "10.2.3.4", expected: "https://internal.example.com/download", found: true},
+		{name: "download", ip: "5.255.255.5", expected: "https://example.com/download", found: true},
+		{name: "docs", ip: "81.2.69.1", expected: "https://en.example.com/docs", found: true},
+		{name: "docs", ip: "193.138.218.226", found: true},
+		{name: "unknown", ip: "193.138.218.226"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/go/"+c.name, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		target, found := cfg.Redirect(c.name, info)
+		if target != c.expected || found != c.found {
+			t.Errorf("%s %s: unexpected target %q %v", c.name, c.ip, target, found)
+		}
+	}
+
+	config = `{"redirects": {"docs": {"default": "https://example.com/docs"}}}`
+	if err = os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, found := cfg.Redirect("docs", &IPInfo{}); !found || target != "https://example.com/docs" {
+		t.Errorf("unexpected reloaded target %q %v", target, found)
+	}
+
+	if _, found := cfg.Redirect("download", &IPInfo{}); found {
+		t.Error("unexpected reloaded redirect")
+	}
+
+	for _, bad := range []string{
+		`{"redirects": {"docs": {"rules": [{"countries": ["SE"]}]}}}`,
+		`{"redirects": {"docs": {"rules": [{"target": "https://example.com"}]}}}`,
+		`{"redirects": {"docs": {"rules": [{"cidrs": ["bad"], "target": "https://example.com"}]}}}`,
+		`{"redirects": {"docs": {"rules": [{"countries": ["SE"], "target": "/docs"}]}}}`,
+		`{"redirects": {"docs": {"rules": [{"countries": ["SE"], "target": "example.com/docs"}]}}}`,
+		`{"redirects": {"docs": {"rules": [{"countries": ["SE"], "target": "ftp://example.com/docs"}]}}}`,
+		`{"redirects": {"docs": {"default": "https:///docs"}}}`,
+		`{"redirects": {"docs": null}}`,
+		`{"redirects": []}`,
+	} {
+		if err = os.WriteFile(configName, []byte(bad), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = cfg.Reload(); err == nil {
+			t.Errorf("expected reload error for %s", bad)
+		}
+	}
+
+	if target, _ := cfg.Redirect("docs", &IPInfo{}); target != "https://example.com/docs" {
+		t.Errorf("rules are changed by failed reload: %q", target)
+	}
+}
@@ -0,0 +1,126 @@
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// executor is a parsed text or HTML template.
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// Template is a user-defined output format, it's executed with IPInfo data like built-in HTML one.
+// Path is URL path of the format, File is a template file, and HTML templates escape values.
+type Template struct {
+	executor    executor
+	Path        string `json:"path"`
+	File        string `json:"file"`
+	ContentType string `json:"content_type"`
+	HTML        bool   `json:"html"`
+}
+
+// Execute writes the template result only if it's executed without errors.
+func (t *Template) Execute(w io.Writer, info *IPInfo) error {
+	var buf bytes.Buffer
+
+	if err := t.executor.Execute(&buf, info); err != nil {
+		return fmt.Errorf("template %q: %w", t.Path, err)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// load checks settings and parses the template file.
+func (t *Template) load() error {
+	if !strings.HasPrefix(t.Path, "/") || strings.TrimRight(t.Path, "/ ") != t.Path {
+		return fmt.Errorf("path %q should start with '/' and have no trailing one", t.Path)
+	}
+
+	if t.File == "" {
+		return errors.New("empty file")
+	}
+
+	content, err := os.ReadFile(t.File)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(t.File)
+	if t.HTML {
+		t.executor, err = htmltemplate.New(name).Parse(string(content))
+	} else {
+		t.executor, err = texttemplate.New(name).Parse(string(content))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if t.ContentType == "" {
+		t.ContentType = "text/plain; charset=utf-8"
+		if t.HTML {
+			t.ContentType = "text/html; charset=utf-8"
+		}
+	}
+	return nil
+}
+
+// Template returns a user-defined template by URL path.
+func (c *Cfg) Template(path string) (*Template, bool) {
+	templates := c.templates.Load()
+	if templates == nil {
+		return nil, false
+	}
+
+	t, ok := (*templates)[path]
+	return t, ok
+}
+
+// ReservePaths sets a check of URL paths which can't be used by templates, e.g. built-in handlers' ones,
+// current templates are checked too. Reloaded templates with reserved paths are rejected.
+func (c *Cfg) ReservePaths(reserved func(path string) bool) error {
+	if templates := c.templates.Load(); templates != nil {
+		for path := range *templates {
+			if reserved(path) {
+				return fmt.Errorf("template path %q is reserved", path)
+			}
+		}
+	}
+
+	c.reserved = reserved
+	return nil
+}
+
+// loadTemplates parses templates and returns them by path, reserved paths are not allowed if the check is set.
+func loadTemplates(templates []*Template, reserved func(path string) bool) (map[string]*Template, error) {
+	result := make(map[string]*Template, len(templates))
+
+	for i, t := range templates {
+		if t == nil {
+			return nil, fmt.Errorf("template %d: empty", i)
+		}
+
+		if err := t.load(); err != nil {
+			return nil, fmt.Errorf("template %d: %w", i, err)
+		}
+
+		if _, ok := result[t.Path]; ok {
+			return nil, fmt.Errorf("template %d: duplicate path %q", i, t.Path)
+		}
+
+		if reserved != nil && reserved(t.Path) {
+			return nil, fmt.Errorf("template %d: path %q is reserved", i, t.Path)
+		}
+		result[t.Path] = t
+	}
+	return result, nil
+}
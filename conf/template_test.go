@@ -0,0 +1,183 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestTemplate_Execute(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ip.tmpl":     "IP={{ .IP }}\nCOUNTRY={{ .Country }}\n",
+		"ip.html":     "<p>{{ .City }}</p>",
+		"bad.tmpl":    "{{ .IP ",
+		"broken.tmpl": "{{ .Unknown }}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := &IPInfo{IP: "127.0.0.1", Country: "Sweden", City: "<Malmo>"}
+	cases := []struct {
+		name        string
+		contentType string
+		expected    string
+		template    Template
+		err         bool
+		execErr     bool
+	}{
+		{
+			name:        "text",
+			template:    Template{Path: "/env", File: "ip.tmpl"},
+			contentType: "text/plain; charset=utf-8",
+			expected:    "IP=127.0.0.1\nCOUNTRY=Sweden\n",
+		},
+		{
+			name:        "html",
+			template:    Template{Path: "/city", File: "ip.html", HTML: true},
+			contentType: "text/html; charset=utf-8",
+			expected:    "<p>&lt;Malmo&gt;</p>",
+		},
+		{
+			name:        "content type",
+			template:    Template{Path: "/env", File: "ip.tmpl", ContentType: "text/x-shellscript"},
+			contentType: "text/x-shellscript",
+			expected:    "IP=127.0.0.1\nCOUNTRY=Sweden\n",
+		},
+		{name: "execution error", template: Template{Path: "/broken", File: "broken.tmpl"}, execErr: true},
+		{name: "parse error", template: Template{Path: "/bad", File: "bad.tmpl"}, err: true},
+		{name: "no file", template: Template{Path: "/none", File: "none.tmpl"}, err: true},
+		{name: "empty file", template: Template{Path: "/none"}, err: true},
+		{name: "relative path", template: Template{Path: "env", File: "ip.tmpl"}, err: true},
+		{name: "trailing slash", template: Template{Path: "/env/", File: "ip.tmpl"}, err: true},
+	}
+	for _, c := range cases {
+		if c.template.File != "" {
+			c.template.File = filepath.Join(dir, c.template.File)
+		}
+
+		err := c.template.load()
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected load error", c.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected load error: %v", c.name, err)
+			continue
+		}
+
+		var buf bytes.Buffer
+		err = c.template.Execute(&buf, info)
+
+		if c.execErr {
+			if err == nil || buf.Len() > 0 {
+				t.Errorf("%s: expected execution error without output: %v %q", c.name, err, buf.String())
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected execution error: %v", c.name, err)
+			continue
+		}
+
+		if ct := c.template.ContentType; ct != c.contentType {
+			t.Errorf("%s: not equal content type %q != %q", c.name, ct, c.contentType)
+		}
+
+		if s := buf.String(); s != c.expected {
+			t.Errorf("%s: not equal result %q != %q", c.name, s, c.expected)
+		}
+	}
+}
+
+func TestCfg_Template(t *testing.T) {
+	const (
+		configName   = "templates.json"
+		templateName = "templates.tmpl"
+	)
+	if err := os.WriteFile(templateName, []byte("{{ .IP }}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := `{"db": "` + mmdbtest.DBName + `", "templates": [{"path": "/ip.txt", "file": "` + templateName + `"}]}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	if _, found := cfg.Template("/ip.txt"); !found {
+		t.Error("template not found")
+	}
+
+	config = `{"templates": [{"path": "/ip", "file": "` + templateName + `", "content_type": "text/csv"}]}`
+	if err = os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := cfg.Template("/ip.txt"); found {
+		t.Error("unexpected reloaded template")
+	}
+
+	if tmpl, found := cfg.Template("/ip"); !found || tmpl.ContentType != "text/csv" {
+		t.Errorf("unexpected reloaded template %v %v", tmpl, found)
+	}
+
+	if err = cfg.ReservePaths(func(path string) bool { return path == "/ip" }); err == nil {
+		t.Error("expected error for reserved path of current template")
+	}
+
+	if err = cfg.ReservePaths(func(path string) bool { return path == "/json" }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{
+		`{"templates": [{"path": "/json", "file": "` + templateName + `"}]}`,
+		`{"templates": [{"path": "/ip", "file": "unknown.tmpl"}]}`,
+		`{"templates": [{"path": "/a", "file": "` + templateName + `"}, {"path": "/a", "file": "` + templateName + `"}]}`,
+		`{"templates": [null]}`,
+		`{"templates": [{"path": "/b", "file": "` + templateName + `"}], "redirects": {"docs": null}}`,
+	} {
+		if err = os.WriteFile(configName, []byte(bad), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = cfg.Reload(); err == nil {
+			t.Errorf("expected reload error for %s", bad)
+		}
+	}
+
+	if _, found := cfg.Template("/ip"); !found {
+		t.Error("templates are changed by failed reload")
+	}
+
+	if _, found := cfg.Template("/b"); found {
+		t.Error("templates are partially changed by failed reload")
+	}
+
+	if _, err = New(configName); err == nil {
+		t.Error("expected error for invalid templates")
+	}
+}
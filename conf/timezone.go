@@ -0,0 +1,124 @@
+package conf
+
+import (
+	"sync"
+	"time"
+)
+
+// transitionSearchDays is a period to search the next time zone transition.
+const transitionSearchDays = 366
+
+// locations is a per-process cache of loaded time zones.
+var locations sync.Map //nolint:gochecknoglobals
+
+// transitions is a per-process cache of the next transitions by location name.
+var transitions sync.Map //nolint:gochecknoglobals
+
+// cachedTransition is the next transition of the location found for moments since "from".
+// It's valid until the transition, if it's not found, the search is repeated a day later.
+type cachedTransition struct {
+	loc  *time.Location
+	from time.Time
+	next time.Time
+	ok   bool
+}
+
+// valid returns true if the transition is actual for the moment in the location.
+func (c *cachedTransition) valid(t time.Time, loc *time.Location) bool {
+	if c.loc != loc || t.Before(c.from) {
+		return false
+	}
+
+	if c.ok {
+		return t.Before(c.next)
+	}
+	return t.Before(c.from.Add(24 * time.Hour))
+}
+
+// TimeZoneInfo is time zone details for some moment.
+type TimeZoneInfo struct {
+	Abbreviation     string `json:"tz_abbreviation"     xml:"tz_abbreviation"     yaml:"tz_abbreviation"`
+	UTCOffset        string `json:"utc_offset"          xml:"utc_offset"          yaml:"utc_offset"`
+	NextTransition   string `json:"next_dst_transition" xml:"next_dst_transition" yaml:"next_dst_transition"`
+	UTCOffsetSeconds int    `json:"utc_offset_seconds"  xml:"utc_offset_seconds"  yaml:"utc_offset_seconds"`
+	IsDST            bool   `json:"is_dst"              xml:"is_dst"              yaml:"is_dst"`
+}
+
+// LoadLocation returns a time zone location by IANA name, loaded locations are cached.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil //nolint:forcetypeassert // only locations are stored
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// NewTimeZoneInfo returns time zone details for the moment in the location.
+func NewTimeZoneInfo(t time.Time, loc *time.Location) TimeZoneInfo {
+	t = t.In(loc)
+	abbreviation, offset := t.Zone()
+
+	info := TimeZoneInfo{
+		Abbreviation:     abbreviation,
+		UTCOffset:        t.Format("-07:00"),
+		UTCOffsetSeconds: offset,
+		IsDST:            t.IsDST(),
+	}
+
+	if next, ok := cachedNextTransition(t, loc); ok {
+		info.NextTransition = next.Format(time.RFC3339)
+	}
+	return info
+}
+
+// cachedNextTransition returns the next transition of the location, it's searched only if the cached one has passed.
+func cachedNextTransition(t time.Time, loc *time.Location) (time.Time, bool) {
+	if c, ok := transitions.Load(loc.String()); ok {
+		if cached := c.(*cachedTransition); cached.valid(t, loc) { //nolint:forcetypeassert // only transitions are stored
+			return cached.next, cached.ok
+		}
+	}
+
+	next, ok := nextTransition(t)
+	transitions.Store(loc.String(), &cachedTransition{loc: loc, from: t, next: next, ok: ok})
+	return next, ok
+}
+
+// nextTransition returns the next moment when the time zone offset or DST flag is changed.
+// It checks days to find a changed one and then searches the exact second by bisection.
+func nextTransition(t time.Time) (time.Time, bool) {
+	_, offset := t.Zone()
+	isDST := t.IsDST()
+
+	changed := func(x time.Time) bool {
+		_, xOffset := x.Zone()
+		return xOffset != offset || x.IsDST() != isDST
+	}
+
+	start := t.Truncate(time.Second)
+	for day := 1; day <= transitionSearchDays; day++ {
+		end := start.AddDate(0, 0, 1)
+		if !changed(end) {
+			start = end
+			continue
+		}
+
+		// the transition is in (start, end]
+		for end.Sub(start) > time.Second {
+			middle := start.Add(end.Sub(start) / 2).Truncate(time.Second)
+			if changed(middle) {
+				end = middle
+			} else {
+				start = middle
+			}
+		}
+		return end, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,133 @@
+package conf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loc != cached {
+		t.Error("location is not cached")
+	}
+
+	if _, err = LoadLocation("invalid"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestNewTimeZoneInfo(t *testing.T) {
+	cases := []struct {
+		name     string
+		timeZone string
+		ts       time.Time
+		expected TimeZoneInfo
+	}{
+		{
+			name:     "utc",
+			ts:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+			expected: TimeZoneInfo{Abbreviation: "UTC", UTCOffset: "+00:00"},
+		},
+		{
+			name:     "winter",
+			timeZone: "Europe/Stockholm",
+			ts:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+			expected: TimeZoneInfo{
+				Abbreviation:     "CET",
+				UTCOffset:        "+01:00",
+				NextTransition:   "2019-03-31T03:00:00+02:00",
+				UTCOffsetSeconds: 3600,
+			},
+		},
+		{
+			name:     "summer",
+			timeZone: "Europe/Stockholm",
+			ts:       time.Date(2019, 7, 2, 3, 4, 5, 6, time.UTC),
+			expected: TimeZoneInfo{
+				Abbreviation:     "CEST",
+				UTCOffset:        "+02:00",
+				NextTransition:   "2019-10-27T02:00:00+01:00",
+				UTCOffsetSeconds: 7200,
+				IsDST:            true,
+			},
+		},
+		{
+			name:     "negative",
+			timeZone: "America/St_Johns",
+			ts:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+			expected: TimeZoneInfo{
+				Abbreviation:     "NST",
+				UTCOffset:        "-03:30",
+				NextTransition:   "2019-03-10T03:00:00-02:30",
+				UTCOffsetSeconds: -12600,
+			},
+		},
+		{
+			name:     "no dst",
+			timeZone: "Asia/Tokyo",
+			ts:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+			expected: TimeZoneInfo{Abbreviation: "JST", UTCOffset: "+09:00", UTCOffsetSeconds: 32400},
+		},
+	}
+	for _, c := range cases {
+		loc, err := LoadLocation(c.timeZone)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		if info := NewTimeZoneInfo(c.ts, loc); info != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.name, info, c.expected)
+		}
+	}
+}
+
+func TestCachedNextTransition(t *testing.T) {
+	loc, err := LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		ts       time.Time
+		expected string
+	}{
+		{ts: time.Date(2019, 7, 2, 3, 4, 5, 0, time.UTC), expected: "2019-10-27T02:00:00+01:00"},
+		{ts: time.Date(2019, 10, 27, 0, 59, 59, 0, time.UTC), expected: "2019-10-27T02:00:00+01:00"},
+		{ts: time.Date(2019, 10, 27, 1, 0, 0, 0, time.UTC), expected: "2020-03-29T03:00:00+02:00"},
+		{ts: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC), expected: "2019-03-31T03:00:00+02:00"},
+	}
+	for _, c := range cases {
+		next, ok := cachedNextTransition(c.ts.In(loc), loc)
+		if s := next.Format(time.RFC3339); !ok || s != c.expected {
+			t.Errorf("%v: not equal %v != %v", c.ts, s, c.expected)
+		}
+	}
+
+	// the same name of another location
+	fixed := time.FixedZone(loc.String(), 3600)
+	if next, ok := cachedNextTransition(time.Date(2019, 1, 2, 3, 4, 5, 0, fixed), fixed); ok {
+		t.Errorf("unexpected transition %v", next)
+	}
+}
+
+func BenchmarkNewTimeZoneInfo(b *testing.B) {
+	loc, err := LoadLocation("Europe/Stockholm")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ts := time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC)
+	b.ReportAllocs()
+	for b.Loop() {
+		NewTimeZoneInfo(ts, loc)
+	}
+}
@@ -0,0 +1,86 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Client types by User-Agent header.
+const (
+	ClientUnknown = ""
+	ClientCLI     = "cli"
+	ClientBrowser = "browser"
+)
+
+var (
+	// DefaultCLIAgents are User-Agent patterns of command line tools.
+	DefaultCLIAgents = []string{`^curl/`, `^Wget/`, `^HTTPie/`, `PowerShell/`} //nolint:gochecknoglobals
+	// DefaultBrowserAgents are User-Agent patterns of browsers.
+	DefaultBrowserAgents = []string{`^Mozilla/`, `^Opera/`} //nolint:gochecknoglobals
+)
+
+// UserAgents are regular expressions of User-Agent header to detect client type for the default output.
+// CLI patterns are checked first, because some tools (PowerShell) have browser-like headers.
+type UserAgents struct {
+	cli      []*regexp.Regexp
+	browsers []*regexp.Regexp
+	CLI      []string `json:"cli"`
+	Browsers []string `json:"browsers"`
+}
+
+// Client returns client type by User-Agent header value.
+func (u *UserAgents) Client(userAgent string) string {
+	switch {
+	case userAgent == "":
+		return ClientUnknown
+	case matchAny(u.cli, userAgent):
+		return ClientCLI
+	case matchAny(u.browsers, userAgent):
+		return ClientBrowser
+	}
+	return ClientUnknown
+}
+
+// compile sets default patterns and compiles them.
+func (u *UserAgents) compile() error {
+	var err error
+
+	if u.CLI == nil {
+		u.CLI = DefaultCLIAgents
+	}
+
+	if u.Browsers == nil {
+		u.Browsers = DefaultBrowserAgents
+	}
+
+	if u.cli, err = compilePatterns(u.CLI); err != nil {
+		return fmt.Errorf("cli: %w", err)
+	}
+
+	if u.browsers, err = compilePatterns(u.Browsers); err != nil {
+		return fmt.Errorf("browsers: %w", err)
+	}
+	return nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, len(patterns))
+
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = re
+	}
+	return result, nil
+}
+
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package conf
+
+import "testing"
+
+func TestUserAgents_Client(t *testing.T) {
+	const browser = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.5 Safari/605.1.15"
+	cases := []struct {
+		name      string
+		userAgent string
+		expected  string
+		agents    UserAgents
+	}{
+		{name: "empty", userAgent: "", expected: ClientUnknown},
+		{name: "curl", userAgent: "curl/8.14.1", expected: ClientCLI},
+		{name: "wget", userAgent: "Wget/1.21.4", expected: ClientCLI},
+		{name: "httpie", userAgent: "HTTPie/3.2.4", expected: ClientCLI},
+		{name: "powershell", userAgent: "Mozilla/5.0 (Windows NT; Windows NT 10.0; en-US) WindowsPowerShell/5.1.19041.1", expected: ClientCLI},
+		{name: "browser", userAgent: browser, expected: ClientBrowser},
+		{name: "other", userAgent: "Go-http-client/1.1", expected: ClientUnknown},
+		{name: "custom", agents: UserAgents{CLI: []string{`^Go-http-client/`}}, userAgent: "Go-http-client/1.1", expected: ClientCLI},
+		{name: "custom curl", agents: UserAgents{CLI: []string{`^Go-http-client/`}}, userAgent: "curl/8.14.1", expected: ClientUnknown},
+		{name: "disabled", agents: UserAgents{CLI: []string{}, Browsers: []string{}}, userAgent: browser, expected: ClientUnknown},
+	}
+	for _, c := range cases {
+		if err := c.agents.compile(); err != nil {
+			t.Fatalf("%s: compile error: %v", c.name, err)
+		}
+
+		if client := c.agents.Client(c.userAgent); client != c.expected {
+			t.Errorf("%s: not equal client %q != %q", c.name, client, c.expected)
+		}
+	}
+
+	bad := UserAgents{Browsers: []string{"("}}
+	if err := bad.compile(); err == nil {
+		t.Error("expected compile error")
+	}
+}
@@ -0,0 +1,78 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// asnTypes are substrings of database types which contain autonomous system data.
+var asnTypes = []string{"asn", "isp"} //nolint:gochecknoglobals
+
+// ASNJoin is a composite locator which adds autonomous system data of ASN locator
+// to records of the main one. ASN database can be MaxMind GeoLite2 ASN or compatible.
+type ASNJoin struct {
+	Locator Locator
+	ASN     Locator
+}
+
+// Lookup returns a record of the main locator with ASN part of the second one.
+// It returns ErrNotFound only if both locators don't know the address.
+func (j *ASNJoin) Lookup(addr netip.Addr) (*Record, error) {
+	result := &Record{}
+
+	record, err := j.Locator.Lookup(addr)
+	switch {
+	case err == nil:
+		*result = *record // copy, because locators can share records
+	case !errors.Is(err, ErrNotFound):
+		return nil, err
+	}
+
+	asn, asnErr := j.ASN.Lookup(addr)
+	if asnErr != nil {
+		if errors.Is(asnErr, ErrNotFound) && err == nil {
+			return result, nil
+		}
+		return nil, asnErr
+	}
+
+	result.ASNRecord = asn.ASNRecord
+	return result, nil
+}
+
+// Close closes both locators.
+func (j *ASNJoin) Close() error {
+	return errors.Join(j.Locator.Close(), j.ASN.Close())
+}
+
+// String returns a short description of locators.
+func (j *ASNJoin) String() string {
+	items := make([]string, 0, 2)
+
+	for _, locator := range []Locator{j.Locator, j.ASN} {
+		if s, ok := locator.(fmt.Stringer); ok {
+			items = append(items, s.String())
+		} else {
+			items = append(items, fmt.Sprintf("%T", locator))
+		}
+	}
+	return strings.Join(items, "; asn ")
+}
+
+func (j *ASNJoin) check(v *Validation, now time.Time) []error {
+	var errs []error
+
+	if c, ok := j.Locator.(checker); ok {
+		errs = c.check(v, now)
+	}
+
+	if db, ok := j.ASN.(*MMDB); ok {
+		for _, err := range db.checkDatabase(v, now, "ASN", asnTypes) {
+			errs = append(errs, fmt.Errorf("asn: %w", err))
+		}
+	}
+	return errs
+}
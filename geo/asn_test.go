@@ -0,0 +1,104 @@
+package geo
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestASNJoin_Lookup(t *testing.T) {
+	city, err := OpenMMDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asn, err := OpenMMDB(mmdbtest.ASNDBName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := &ASNJoin{Locator: city, ASN: asn}
+	defer func() {
+		if closeErr := join.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cases := []struct {
+		addr    string
+		city    string
+		asn     ASNRecord
+		missing bool
+	}{
+		{addr: benchIP, city: "Malmo", asn: ASNRecord{ASN: 39351, ASOrganization: "31173 Services AB"}},
+		{addr: "5.255.255.5", city: "Moscow"},
+		{addr: "1.1.1.1", asn: ASNRecord{ASN: 13335, ASOrganization: "Cloudflare, Inc."}},
+		{addr: "127.0.0.1", missing: true},
+	}
+	for _, c := range cases {
+		record, lookupErr := join.Lookup(netip.MustParseAddr(c.addr))
+		if c.missing {
+			if !errors.Is(lookupErr, ErrNotFound) {
+				t.Errorf("%s: expected not found error, got %v", c.addr, lookupErr)
+			}
+			continue
+		}
+
+		if lookupErr != nil {
+			t.Errorf("%s: lookup error: %v", c.addr, lookupErr)
+			continue
+		}
+
+		if record.City.Names.EN != c.city || record.ASNRecord != c.asn {
+			t.Errorf("%s: unexpected record %v", c.addr, *record)
+		}
+	}
+
+	if s := join.String(); !strings.Contains(s, "GeoLite2-City") || !strings.Contains(s, "; asn mmdb: MaxMind GeoLite2-ASN") {
+		t.Errorf("unexpected description: %v", s)
+	}
+
+	if err = (&Validation{}).Validate(join, time.Now()); err != nil {
+		t.Errorf("validation error: %v", err)
+	}
+
+	swapped := &ASNJoin{Locator: asn, ASN: city}
+	err = (&Validation{}).Validate(swapped, time.Now())
+	expected := `asn: database type "GeoLite2-City" is not ASN compatible`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestSetASN(t *testing.T) {
+	cases := []struct {
+		value    any
+		expected uint32
+		fail     bool
+	}{
+		{value: uint32(13335), expected: 13335},
+		{value: "AS13335", expected: 13335},
+		{value: "as2914", expected: 2914},
+		{value: "39351", expected: 39351},
+		{value: "ASN", fail: true},
+		{value: -1, fail: true},
+		{value: true, fail: true},
+	}
+	for _, c := range cases {
+		var number uint32
+
+		err := setASN(&number, c.value)
+		if (err != nil) != c.fail {
+			t.Errorf("%v: unexpected error: %v", c.value, err)
+			continue
+		}
+
+		if number != c.expected {
+			t.Errorf("%v: not equal %v != %v", c.value, number, c.expected)
+		}
+	}
+}
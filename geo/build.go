@@ -0,0 +1,50 @@
+package geo
+
+import "time"
+
+// builder is implemented by locators which know build time of their databases.
+type builder interface {
+	buildTime() time.Time
+}
+
+// BuildTime returns the oldest build time of the locator's databases in UTC,
+// it's zero if the locator doesn't know it, e.g. for CSV files.
+func BuildTime(locator Locator) time.Time {
+	if b, ok := locator.(builder); ok {
+		return b.buildTime()
+	}
+	return time.Time{}
+}
+
+func (m *MMDB) buildTime() time.Time {
+	return m.reader.Metadata.BuildTime().UTC()
+}
+
+func (j *ASNJoin) buildTime() time.Time {
+	return oldestBuildTime(j.Locator, j.ASN)
+}
+
+func (m Merge) buildTime() time.Time {
+	locators := make([]Locator, len(m))
+
+	for i, source := range m {
+		locators[i] = source.Locator
+	}
+	return oldestBuildTime(locators...)
+}
+
+func (c Chain) buildTime() time.Time {
+	return oldestBuildTime(c...)
+}
+
+// oldestBuildTime returns the oldest known build time of locators.
+func oldestBuildTime(locators ...Locator) time.Time {
+	var result time.Time
+
+	for _, locator := range locators {
+		if t := BuildTime(locator); !t.IsZero() && (result.IsZero() || t.Before(result)) {
+			result = t
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package geo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildTime(t *testing.T) {
+	city, err := OpenMMDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := city.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	expected := city.reader.Metadata.BuildTime().UTC()
+	if d := time.Since(expected); d < 0 || d > time.Hour {
+		t.Fatalf("unexpected fixture build time %v", expected)
+	}
+
+	cases := []struct {
+		expected time.Time
+		locator  Locator
+		name     string
+	}{
+		{name: "mmdb", locator: city, expected: expected},
+		{name: "empty chain", locator: Chain{}},
+		{name: "chain", locator: Chain{Chain{}, city}, expected: expected},
+		{name: "merge", locator: Merge{{Name: "empty", Locator: Chain{}}, {Name: "city", Locator: city}}, expected: expected},
+		{name: "asn", locator: &ASNJoin{Locator: Chain{}, ASN: city}, expected: expected},
+	}
+	for _, c := range cases {
+		if bt := BuildTime(c.locator); !bt.Equal(c.expected) {
+			t.Errorf("%s: not equal %v != %v", c.name, bt, c.expected)
+		}
+	}
+}
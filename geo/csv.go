@@ -0,0 +1,220 @@
+package geo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// CSV is an in-memory locator loaded from GeoLite2 City CSV files.
+type CSV struct {
+	*Table
+	files    int
+	heapSize uint64
+	loadTime time.Duration
+}
+
+// csvLocation is a geoname record from locations files, it's merged for all loaded locales.
+type csvLocation struct {
+	continent   ContinentRecord
+	country     CountryRecord
+	subdivision SubdivisionRecord
+	city        CityRecord
+	timeZone    string
+}
+
+// csvRecordKey is a key to share records of networks with the same location.
+type csvRecordKey struct {
+	geonameID      string
+	latitude       string
+	longitude      string
+	accuracyRadius string
+}
+
+// LoadCSV loads GeoLite2 City CSV blocks files (IPv4 and/or IPv6) and locations files.
+// Every locations file has names for one locale, so all locales should be loaded
+// to have the same names and languages as the City mmdb database.
+func LoadCSV(blocks, locations []string) (*CSV, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no csv blocks files")
+	}
+
+	start, before := time.Now(), heapAlloc()
+	geonames := make(map[string]*csvLocation)
+
+	for _, filename := range locations {
+		if err := readCSV(filename, func(row csvRow) error { return loadLocation(geonames, row) }); err != nil {
+			return nil, fmt.Errorf("locations %q: %w", filename, err)
+		}
+	}
+
+	table := NewTable()
+	records := make(map[csvRecordKey]*Record)
+
+	for _, filename := range blocks {
+		err := readCSV(filename, func(row csvRow) error { return loadBlock(table, records, geonames, row) })
+		if err != nil {
+			return nil, fmt.Errorf("blocks %q: %w", filename, err)
+		}
+	}
+
+	var heapSize uint64
+	if after := heapAlloc(); after > before {
+		heapSize = after - before
+	}
+
+	return &CSV{
+		Table:    table,
+		files:    len(blocks) + len(locations),
+		heapSize: heapSize,
+		loadTime: time.Since(start),
+	}, nil
+}
+
+// String returns a short description of loaded data.
+func (c *CSV) String() string {
+	return fmt.Sprintf(
+		"csv: %d files, %d networks, loaded in %v, memory %.1f MiB",
+		c.files, c.Len(), c.loadTime.Round(time.Millisecond), float64(c.heapSize)/(1<<20),
+	)
+}
+
+func loadLocation(geonames map[string]*csvLocation, row csvRow) error {
+	geonameID := row.get("geoname_id")
+	if geonameID == "" {
+		return errors.New("empty geoname_id")
+	}
+
+	location, ok := geonames[geonameID]
+	if !ok {
+		location = &csvLocation{
+			continent:   ContinentRecord{Code: row.get("continent_code")},
+			country:     CountryRecord{ISOCode: row.get("country_iso_code")},
+			subdivision: SubdivisionRecord{ISOCode: row.get("subdivision_1_iso_code")},
+			timeZone:    row.get("time_zone"),
+		}
+		geonames[geonameID] = location
+	}
+
+	locale := row.get("locale_code")
+	location.country.Names.set(locale, row.get("country_name"))
+	location.subdivision.Names.set(locale, row.get("subdivision_1_name"))
+	location.city.Names.set(locale, row.get("city_name"))
+	return nil
+}
+
+func loadBlock(table *Table, records map[csvRecordKey]*Record, geonames map[string]*csvLocation, row csvRow) error {
+	prefix, err := netip.ParsePrefix(row.get("network"))
+	if err != nil {
+		return err
+	}
+
+	key := csvRecordKey{
+		geonameID:      row.get("geoname_id"),
+		latitude:       row.get("latitude"),
+		longitude:      row.get("longitude"),
+		accuracyRadius: row.get("accuracy_radius"),
+	}
+	if record, ok := records[key]; ok {
+		table.Add(prefix, record)
+		return nil
+	}
+
+	record := &Record{}
+	if location, ok := geonames[key.geonameID]; ok {
+		record.Continent = location.continent
+		record.Country = location.country
+		record.Subdivision = location.subdivision
+		record.City = location.city
+		record.Location.TimeZone = location.timeZone
+	}
+
+	if key.latitude != "" {
+		if record.Location.Latitude, err = strconv.ParseFloat(key.latitude, 64); err != nil {
+			return fmt.Errorf("latitude: %w", err)
+		}
+	}
+
+	if key.longitude != "" {
+		if record.Location.Longitude, err = strconv.ParseFloat(key.longitude, 64); err != nil {
+			return fmt.Errorf("longitude: %w", err)
+		}
+	}
+
+	if key.accuracyRadius != "" {
+		radius, parseErr := strconv.ParseUint(key.accuracyRadius, 10, 16)
+		if parseErr != nil {
+			return fmt.Errorf("accuracy radius: %w", parseErr)
+		}
+		record.Location.AccuracyRadius = uint16(radius)
+	}
+
+	records[key] = record
+	table.Add(prefix, record)
+	return nil
+}
+
+// csvRow is a CSV row with column indexes from the header.
+type csvRow struct {
+	columns map[string]int
+	values  []string
+}
+
+func (r csvRow) get(name string) string {
+	if i, ok := r.columns[name]; ok && i < len(r.values) {
+		return r.values[i]
+	}
+	return ""
+}
+
+// readCSV reads a CSV file with a header row and calls handler for every data row.
+func readCSV(filename string, handler func(row csvRow) error) (err error) {
+	f, err := os.Open(filename) //nolint:gosec // file name is from configuration
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	reader := csv.NewReader(f)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+
+	row := csvRow{columns: make(map[string]int, len(header))}
+	for i, name := range header {
+		row.columns[name] = i
+	}
+
+	for line := 2; ; line++ {
+		row.values, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = handler(row); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
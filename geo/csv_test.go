@@ -0,0 +1,143 @@
+package geo
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testBlocksIPv4 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,` +
+		`is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius,is_anycast
+193.138.218.0/24,2692969,2661886,,0,0,"211 19",55.6078,12.9982,20,
+193.138.219.0/24,2692969,2661886,,0,0,"211 19",55.6078,12.9982,20,
+81.2.69.0/24,,2635167,,0,0,,,,,
+`
+	testBlocksIPv6 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,` +
+		`is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius,is_anycast
+2a02:d40::/32,2661886,2661886,,0,0,,59.3247,18.0560,100,
+`
+	testLocationsEN = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,` +
+		`subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,` +
+		`metro_code,time_zone,is_in_european_union
+2692969,en,EU,Europe,SE,Sweden,M,"Skane County",,,Malmo,,Europe/Stockholm,1
+2661886,en,EU,Europe,SE,Sweden,,,,,,,Europe/Stockholm,1
+`
+	testLocationsRU = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,` +
+		`subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,` +
+		`metro_code,time_zone,is_in_european_union
+2692969,ru,EU,Европа,SE,Швеция,M,Сконе,,,Мальмё,,Europe/Stockholm,1
+2661886,ru,EU,Европа,SE,Швеция,,,,,,,Europe/Stockholm,1
+`
+)
+
+func writeTestFiles(t *testing.T, contents ...string) []string {
+	dir := t.TempDir()
+	result := make([]string, len(contents))
+
+	for i, content := range contents {
+		result[i] = filepath.Join(dir, strings.Repeat("f", i+1)+".csv")
+		if err := os.WriteFile(result[i], []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return result
+}
+
+func TestLoadCSV(t *testing.T) {
+	files := writeTestFiles(t, testBlocksIPv4, testBlocksIPv6, testLocationsEN, testLocationsRU)
+
+	db, err := LoadCSV(files[:2], files[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	if s := db.String(); !strings.HasPrefix(s, "csv: 4 files, 4 networks, loaded in") {
+		t.Errorf("unexpected description: %v", s)
+	}
+
+	malmo := Record{
+		City:        CityRecord{Names: Names{EN: "Malmo", RU: "Мальмё"}},
+		Subdivision: SubdivisionRecord{Names: Names{EN: "Skane County", RU: "Сконе"}, ISOCode: "M"},
+		Continent:   ContinentRecord{Code: "EU"},
+		Country:     CountryRecord{Names: Names{EN: "Sweden", RU: "Швеция"}, ISOCode: "SE"},
+		Location:    LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 55.6078, Longitude: 12.9982, AccuracyRadius: 20},
+	}
+	cases := []struct {
+		expected *Record
+		addr     string
+	}{
+		{addr: "193.138.218.226", expected: &malmo},
+		{addr: "193.138.219.1", expected: &malmo},
+		{
+			addr: "2a02:d40::1",
+			expected: &Record{
+				Continent: ContinentRecord{Code: "EU"},
+				Country:   CountryRecord{Names: Names{EN: "Sweden", RU: "Швеция"}, ISOCode: "SE"},
+				Location:  LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 59.3247, Longitude: 18.0560, AccuracyRadius: 100},
+			},
+		},
+		{addr: "81.2.69.1", expected: &Record{}},
+		{addr: "127.0.0.1"},
+	}
+	for _, c := range cases {
+		record, lookupErr := db.Lookup(netip.MustParseAddr(c.addr))
+		if c.expected == nil {
+			if !errors.Is(lookupErr, ErrNotFound) {
+				t.Errorf("%s: expected not found error, got %v", c.addr, lookupErr)
+			}
+			continue
+		}
+
+		if lookupErr != nil {
+			t.Errorf("%s: unexpected error: %v", c.addr, lookupErr)
+			continue
+		}
+
+		if *record != *c.expected {
+			t.Errorf("%s: not equal %v != %v", c.addr, *record, *c.expected)
+		}
+	}
+
+	first, _ := db.Lookup(netip.MustParseAddr("193.138.218.1"))
+	second, _ := db.Lookup(netip.MustParseAddr("193.138.219.1"))
+	if first != second {
+		t.Error("records of the same location are not shared")
+	}
+}
+
+func TestLoadCSV_Errors(t *testing.T) {
+	files := writeTestFiles(t,
+		testBlocksIPv4,
+		testLocationsEN,
+		"network,geoname_id\nbad,1\n",
+		"network,latitude\n10.0.0.0/8,north\n",
+		"",
+	)
+
+	cases := []struct {
+		name      string
+		blocks    []string
+		locations []string
+	}{
+		{name: "no blocks", locations: files[1:2]},
+		{name: "bad path", blocks: []string{"/bad_file_path.csv"}},
+		{name: "bad network", blocks: files[2:3]},
+		{name: "bad latitude", blocks: files[3:4]},
+		{name: "empty file", blocks: files[4:5]},
+		{name: "blocks as locations", blocks: files[:1], locations: files[:1]},
+	}
+	for _, c := range cases {
+		if _, err := LoadCSV(c.blocks, c.locations); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
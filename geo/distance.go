@@ -0,0 +1,68 @@
+package geo
+
+import "math"
+
+// EarthRadius is the mean Earth radius in kilometers.
+const EarthRadius = 6371.0088
+
+// Point is a geographic position in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Valid returns true if coordinates are in the allowed ranges.
+func (p Point) Valid() bool {
+	return math.Abs(p.Latitude) <= 90 && math.Abs(p.Longitude) <= 180
+}
+
+// Distance returns the great-circle distance between points in kilometers, it uses the haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat, dLon := lat2-lat1, radians(b.Longitude-a.Longitude)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// Bearing returns the initial bearing from a to b in degrees, clockwise from north in the range [0, 360).
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLon := radians(b.Longitude - a.Longitude)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination returns the point at distance in kilometers from p by initial bearing in degrees.
+// Longitude is normalized to the range [-180, 180].
+func Destination(p Point, bearing, distance float64) Point {
+	lat1, lon1 := radians(p.Latitude), radians(p.Longitude)
+	theta, delta := radians(bearing), distance/EarthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lon2 := lon1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	return Point{Latitude: degrees(lat2), Longitude: math.Mod(degrees(lon2)+540, 360) - 180}
+}
+
+// Circle returns a closed counterclockwise ring of n points around center with radius in kilometers.
+func Circle(center Point, radius float64, n int) []Point {
+	ring := make([]Point, n+1)
+
+	for i := range n {
+		ring[i] = Destination(center, 360-float64(i)*360/float64(n), radius)
+	}
+
+	ring[n] = ring[0]
+	return ring
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,116 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	var (
+		malmo  = Point{Latitude: 55.6078, Longitude: 12.9982}
+		london = Point{Latitude: 51.5142, Longitude: -0.0931}
+		tokyo  = Point{Latitude: 35.69, Longitude: 139.69}
+	)
+	cases := []struct {
+		name     string
+		a, b     Point
+		distance float64
+		bearing  float64
+	}{
+		{name: "same", a: malmo, b: malmo, distance: 0, bearing: 0},
+		{name: "malmo-london", a: malmo, b: london, distance: 975.0, bearing: 247.6},
+		{name: "london-malmo", a: london, b: malmo, distance: 975.0, bearing: 57.1},
+		{name: "london-tokyo", a: london, b: tokyo, distance: 9556.7, bearing: 31.7},
+		{name: "north", a: Point{}, b: Point{Latitude: 90}, distance: 10007.5, bearing: 0},
+		{name: "antipode", a: Point{Latitude: 0, Longitude: 90}, b: Point{Latitude: 0, Longitude: -90}, distance: 20015.1},
+	}
+	for _, c := range cases {
+		if d := Distance(c.a, c.b); math.Abs(d-c.distance) > 0.1 {
+			t.Errorf("%s: not equal distance %v != %v", c.name, d, c.distance)
+		}
+		if c.name == "antipode" {
+			continue // any direction
+		}
+		if b := Bearing(c.a, c.b); math.Abs(b-c.bearing) > 0.1 {
+			t.Errorf("%s: not equal bearing %v != %v", c.name, b, c.bearing)
+		}
+	}
+}
+
+func TestPoint_Valid(t *testing.T) {
+	cases := []struct {
+		point    Point
+		expected bool
+	}{
+		{point: Point{Latitude: 55.6, Longitude: 12.9}, expected: true},
+		{point: Point{Latitude: -90, Longitude: 180}, expected: true},
+		{point: Point{Latitude: 90.1}},
+		{point: Point{Longitude: -180.5}},
+		{point: Point{Latitude: math.NaN()}},
+	}
+	for _, c := range cases {
+		if v := c.point.Valid(); v != c.expected {
+			t.Errorf("%v: not equal %v != %v", c.point, v, c.expected)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	var (
+		malmo  = Point{Latitude: 55.6078, Longitude: 12.9982}
+		london = Point{Latitude: 51.5142, Longitude: -0.0931}
+	)
+	cases := []struct {
+		name     string
+		start    Point
+		bearing  float64
+		distance float64
+	}{
+		{name: "malmo-london", start: malmo, bearing: 247.6, distance: 975.0},
+		{name: "north", start: london, bearing: 0, distance: 100},
+		{name: "antimeridian", start: Point{Latitude: 10, Longitude: 179.9}, bearing: 90, distance: 50},
+	}
+	for _, c := range cases {
+		p := Destination(c.start, c.bearing, c.distance)
+		if !p.Valid() {
+			t.Errorf("%s: invalid point %v", c.name, p)
+		}
+
+		if d := Distance(c.start, p); math.Abs(d-c.distance) > 0.1 {
+			t.Errorf("%s: not equal distance %v != %v", c.name, d, c.distance)
+		}
+
+		if b := Bearing(c.start, p); math.Abs(math.Remainder(b-c.bearing, 360)) > 0.1 {
+			t.Errorf("%s: not equal bearing %v != %v", c.name, b, c.bearing)
+		}
+	}
+
+	if p := Destination(malmo, 247.6, 975.0); math.Abs(p.Latitude-london.Latitude) > 0.05 || math.Abs(p.Longitude-london.Longitude) > 0.05 {
+		t.Errorf("not equal destination %v != %v", p, london)
+	}
+}
+
+func TestCircle(t *testing.T) {
+	center := Point{Latitude: 55.6078, Longitude: 12.9982}
+	ring := Circle(center, 20, 32)
+
+	if n := len(ring); n != 33 {
+		t.Fatalf("not equal ring length %d", n)
+	}
+
+	if ring[0] != ring[32] {
+		t.Errorf("not closed ring %v != %v", ring[0], ring[32])
+	}
+
+	var area float64 // shoelace formula, positive for counterclockwise ring
+	for i, p := range ring[:32] {
+		if d := Distance(center, p); math.Abs(d-20) > 0.001 {
+			t.Errorf("point %d: not equal distance %v", i, d)
+		}
+		area += p.Longitude*ring[i+1].Latitude - ring[i+1].Longitude*p.Latitude
+	}
+
+	if area <= 0 {
+		t.Errorf("ring is not counterclockwise: %v", area)
+	}
+}
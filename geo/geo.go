@@ -0,0 +1,51 @@
+// Copyright 2025 Aleksandr Zaitsev <me@axv.email>.
+// All rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the LICENSE file.
+
+// Package geo contains geo lookup providers.
+package geo
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// DefaultLanguage is a language code of names if a country has no name in its own language.
+const DefaultLanguage = "en"
+
+// ErrNotFound is returned by a Locator if there is no record for the address.
+var ErrNotFound = errors.New("record not found")
+
+// Locator is a geo lookup provider.
+// Lookup must be safe for concurrent use and return ErrNotFound if the address is unknown.
+type Locator interface {
+	Lookup(addr netip.Addr) (*Record, error)
+	Close() error
+}
+
+// Chain is a composite locator, it returns the first found record of its items.
+type Chain []Locator
+
+// Lookup returns a record from the first locator which knows the address.
+func (c Chain) Lookup(addr netip.Addr) (*Record, error) {
+	for _, locator := range c {
+		record, err := locator.Lookup(addr)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return record, err
+	}
+	return nil, ErrNotFound
+}
+
+// Close closes all chain locators.
+func (c Chain) Close() error {
+	var errs []error
+
+	for _, locator := range c {
+		if err := locator.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
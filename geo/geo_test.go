@@ -0,0 +1,120 @@
+package geo
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+type errLocator struct {
+	err error
+}
+
+func (e errLocator) Lookup(netip.Addr) (*Record, error) {
+	return nil, e.err
+}
+
+func (e errLocator) Close() error {
+	return e.err
+}
+
+func testTable() *Table {
+	table := NewTable()
+	table.Add(netip.MustParsePrefix("10.0.0.0/8"), &Record{City: CityRecord{Names: Names{EN: "A"}}})
+	table.Add(netip.MustParsePrefix("10.1.2.3/16"), &Record{City: CityRecord{Names: Names{EN: "B"}}})
+	table.Add(netip.MustParsePrefix("10.1.2.0/24"), &Record{City: CityRecord{Names: Names{EN: "C"}}})
+	table.Add(netip.MustParsePrefix("2001:db8::/32"), &Record{City: CityRecord{Names: Names{EN: "D"}}})
+	return table
+}
+
+func TestTable_Lookup(t *testing.T) {
+	table := testTable()
+	if n := table.Len(); n != 4 {
+		t.Errorf("unexpected length %d", n)
+	}
+
+	cases := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "10.200.0.1", expected: "A"},
+		{addr: "10.1.200.1", expected: "B"},
+		{addr: "10.1.2.200", expected: "C"},
+		{addr: "::ffff:10.1.2.200", expected: "C"},
+		{addr: "2001:db8::1", expected: "D"},
+		{addr: "2001:db9::1"},
+		{addr: "11.0.0.1"},
+	}
+	for _, c := range cases {
+		record, err := table.Lookup(netip.MustParseAddr(c.addr))
+		if c.expected == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: expected not found error, got %v", c.addr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.addr, err)
+			continue
+		}
+
+		if name := record.City.Names.EN; name != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.addr, name, c.expected)
+		}
+	}
+
+	if err := table.Close(); err != nil {
+		t.Errorf("close error: %v", err)
+	}
+}
+
+func TestChain_Lookup(t *testing.T) {
+	second := NewTable()
+	second.Add(netip.MustParsePrefix("11.0.0.0/8"), &Record{City: CityRecord{Names: Names{EN: "E"}}})
+
+	chain := Chain{testTable(), second}
+	cases := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "10.1.2.200", expected: "C"},
+		{addr: "11.0.0.1", expected: "E"},
+		{addr: "12.0.0.1"},
+	}
+	for _, c := range cases {
+		record, err := chain.Lookup(netip.MustParseAddr(c.addr))
+		if c.expected == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: expected not found error, got %v", c.addr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.addr, err)
+			continue
+		}
+
+		if name := record.City.Names.EN; name != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.addr, name, c.expected)
+		}
+	}
+
+	if err := chain.Close(); err != nil {
+		t.Errorf("close error: %v", err)
+	}
+}
+
+func TestChain_Errors(t *testing.T) {
+	failed := errors.New("failed")
+	chain := Chain{errLocator{err: ErrNotFound}, errLocator{err: failed}, testTable()}
+
+	if _, err := chain.Lookup(netip.MustParseAddr("10.0.0.1")); !errors.Is(err, failed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := chain.Close(); !errors.Is(err, failed) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected close error: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Source is a named locator.
+type Source struct {
+	Locator Locator
+	Name    string
+}
+
+// Merge is a composite locator which fills record parts field by field.
+// Every part (country, city, location and ASN) is taken from the first source which has it,
+// and record Sources contain names of used sources.
+type Merge []Source
+
+// Lookup returns a record merged from all sources which know the address.
+func (m Merge) Lookup(addr netip.Addr) (*Record, error) {
+	var (
+		result = &Record{}
+		found  bool
+	)
+
+	for _, source := range m {
+		record, err := source.Locator.Lookup(addr)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("source %q: %w", source.Name, err)
+		}
+
+		found = true
+		if result.Country.IsEmpty() && !record.Country.IsEmpty() {
+			result.Country, result.Continent, result.Sources.Country = record.Country, record.Continent, source.Name
+		}
+
+		if result.City.IsEmpty() && !record.City.IsEmpty() {
+			result.City, result.Subdivision, result.Sources.City = record.City, record.Subdivision, source.Name
+		}
+
+		if result.Location.IsEmpty() && !record.Location.IsEmpty() {
+			result.Location, result.Sources.Location = record.Location, source.Name
+		}
+
+		if result.ASNRecord.IsEmpty() && !record.ASNRecord.IsEmpty() {
+			result.ASNRecord, result.Sources.ASN = record.ASNRecord, source.Name
+		}
+
+		if !(result.Country.IsEmpty() || result.City.IsEmpty() || result.Location.IsEmpty() || result.ASNRecord.IsEmpty()) {
+			break // all parts are filled
+		}
+	}
+
+	if !found {
+		return nil, ErrNotFound
+	}
+	return result, nil
+}
+
+// Close closes all sources.
+func (m Merge) Close() error {
+	var errs []error
+
+	for _, source := range m {
+		if err := source.Locator.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("source %q: %w", source.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// String returns a short description of sources.
+func (m Merge) String() string {
+	items := make([]string, len(m))
+
+	for i, source := range m {
+		items[i] = source.Name
+		if s, ok := source.Locator.(fmt.Stringer); ok {
+			items[i] += " (" + s.String() + ")"
+		}
+	}
+	return "merge: " + strings.Join(items, ", ")
+}
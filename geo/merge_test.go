@@ -0,0 +1,86 @@
+package geo
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestMerge_Lookup(t *testing.T) {
+	cities := NewTable()
+	cities.Add(netip.MustParsePrefix("10.0.0.0/8"), &Record{
+		City:     CityRecord{Names: Names{EN: "A"}},
+		Location: LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 1, Longitude: 2},
+	})
+
+	countries := NewTable()
+	countries.Add(netip.MustParsePrefix("10.0.0.0/8"), &Record{
+		Continent: ContinentRecord{Code: "EU"},
+		Country:   CountryRecord{Names: Names{EN: "Sweden"}, ISOCode: "SE"},
+		Location:  LocationRecord{Latitude: 3, Longitude: 4},
+	})
+	countries.Add(netip.MustParsePrefix("11.0.0.0/8"), &Record{
+		Country: CountryRecord{Names: Names{EN: "Norway"}, ISOCode: "NO"},
+	})
+
+	merge := Merge{
+		{Name: "empty", Locator: NewTable()},
+		{Name: "cities", Locator: cities},
+		{Name: "countries", Locator: countries},
+	}
+
+	record, err := merge.Lookup(netip.MustParseAddr("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Record{
+		Sources:   Sources{Country: "countries", City: "cities", Location: "cities"},
+		City:      CityRecord{Names: Names{EN: "A"}},
+		Continent: ContinentRecord{Code: "EU"},
+		Country:   CountryRecord{Names: Names{EN: "Sweden"}, ISOCode: "SE"},
+		Location:  LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 1, Longitude: 2},
+	}
+	if *record != expected {
+		t.Errorf("not equal %v != %v", *record, expected)
+	}
+
+	record, err = merge.Lookup(netip.MustParseAddr("11.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = Record{
+		Sources: Sources{Country: "countries"},
+		Country: CountryRecord{Names: Names{EN: "Norway"}, ISOCode: "NO"},
+	}
+	if *record != expected {
+		t.Errorf("not equal %v != %v", *record, expected)
+	}
+
+	if _, err = merge.Lookup(netip.MustParseAddr("12.0.0.1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	if s := merge.String(); s != "merge: empty, cities, countries" {
+		t.Errorf("unexpected description: %v", s)
+	}
+
+	if err = merge.Close(); err != nil {
+		t.Errorf("close error: %v", err)
+	}
+}
+
+func TestMerge_Errors(t *testing.T) {
+	failed := errors.New("failed")
+	merge := Merge{{Name: "ok", Locator: testTable()}, {Name: "bad", Locator: errLocator{err: failed}}}
+
+	if _, err := merge.Lookup(netip.MustParseAddr("10.0.0.1")); !errors.Is(err, failed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := merge.Close(); !errors.Is(err, failed) || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("unexpected close error: %v", err)
+	}
+}
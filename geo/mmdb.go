@@ -0,0 +1,199 @@
+package geo
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// maxMMDBSize is a limit of a database size which is loaded into memory, it prevents decompression bombs.
+const maxMMDBSize = 1 << 30
+
+// MMDB is a locator for MaxMind DB files, including other vendors' files with a different schema.
+type MMDB struct {
+	reader   *maxminddb.Reader
+	vendor   string
+	mode     string
+	mappings []fieldMapping
+	size     int64
+}
+
+// OpenMMDB opens a MaxMind DB file using memory map, its schema is detected by the metadata database type.
+// Custom vendors are checked before BuiltinVendors, unknown types are read as MaxMind City.
+// Compressed files can't be mapped, so they are loaded into memory as LoadMMDB does.
+func OpenMMDB(filename string, vendors ...Vendor) (*MMDB, error) {
+	if isCompressed(filename) {
+		return LoadMMDB(filename, vendors...)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := maxminddb.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newMMDB(reader, "mmap", info.Size(), vendors)
+}
+
+// LoadMMDB reads a MaxMind DB file fully into memory, it can be gzip compressed (.mmdb.gz)
+// or be a part of a gzip compressed tar archive (.tar.gz or .tgz) as MaxMind distributes them.
+func LoadMMDB(filename string, vendors ...Vendor) (*MMDB, error) {
+	data, err := readMMDB(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := maxminddb.OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return newMMDB(reader, "memory", int64(len(data)), vendors)
+}
+
+func newMMDB(reader *maxminddb.Reader, mode string, size int64, vendors []Vendor) (*MMDB, error) {
+	var err error
+
+	m := &MMDB{reader: reader, vendor: "unknown", mode: mode, size: size}
+	if vendor, ok := findVendor(reader.Metadata.DatabaseType, vendors); ok {
+		m.vendor = vendor.String()
+		if m.mappings, err = vendor.mappings(); err != nil {
+			return nil, errors.Join(err, reader.Close())
+		}
+	}
+	return m, nil
+}
+
+// Lookup returns compact geo record found by IP address.
+func (m *MMDB) Lookup(addr netip.Addr) (*Record, error) {
+	result := m.reader.Lookup(addr.Unmap().WithZone(""))
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	if !result.Found() {
+		return nil, ErrNotFound
+	}
+
+	record := &Record{}
+	if len(m.mappings) == 0 {
+		if err := result.Decode(record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	for _, mapping := range m.mappings {
+		var value any
+		if err := result.DecodePath(&value, mapping.path...); err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			continue // no such field
+		}
+
+		if err := mapping.set(record, value); err != nil {
+			return nil, fmt.Errorf("field %v: %w", mapping.path, err)
+		}
+	}
+	return record, nil
+}
+
+// City returns full city info found by IP address.
+// It decodes all available fields, so it is much slower than Lookup.
+func (m *MMDB) City(addr netip.Addr) (*geoip2.City, error) {
+	city := &geoip2.City{}
+	if err := m.reader.Lookup(addr.Unmap().WithZone("")).Decode(city); err != nil {
+		return nil, err
+	}
+	return city, nil
+}
+
+// Close closes database file.
+func (m *MMDB) Close() error {
+	return m.reader.Close()
+}
+
+// String returns a short description of the database.
+func (m *MMDB) String() string {
+	return fmt.Sprintf(
+		"mmdb: %s %s, built %s, %s %.1f MiB",
+		m.vendor, m.reader.Metadata.DatabaseType, m.reader.Metadata.BuildTime().UTC().Format(time.DateOnly),
+		m.mode, float64(m.size)/(1<<20),
+	)
+}
+
+func isCompressed(filename string) bool {
+	return strings.HasSuffix(filename, ".gz") || strings.HasSuffix(filename, ".tgz")
+}
+
+// readMMDB reads a database file content, decompressing it if needed.
+func readMMDB(filename string) (data []byte, err error) {
+	if !isCompressed(filename) {
+		return os.ReadFile(filename) //nolint:gosec // file name is from configuration
+	}
+
+	f, err := os.Open(filename) //nolint:gosec // file name is from configuration
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("gzip %q: %w", filename, err)
+	}
+	defer func() {
+		err = errors.Join(err, gz.Close())
+	}()
+
+	var r io.Reader = gz
+	if strings.HasSuffix(filename, ".tar.gz") || strings.HasSuffix(filename, ".tgz") {
+		if r, err = findTarMMDB(gz); err != nil {
+			return nil, fmt.Errorf("tar %q: %w", filename, err)
+		}
+	}
+
+	data, err = io.ReadAll(io.LimitReader(r, maxMMDBSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress %q: %w", filename, err)
+	}
+
+	if len(data) > maxMMDBSize {
+		return nil, fmt.Errorf("decompressed %q is too large", filename)
+	}
+	return data, nil
+}
+
+// findTarMMDB returns a reader of the first mmdb file in tar archive.
+func findTarMMDB(r io.Reader) (io.Reader, error) {
+	archive := tar.NewReader(r)
+
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no mmdb file in archive")
+			}
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && strings.HasSuffix(header.Name, ".mmdb") {
+			return archive, nil
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package geo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+	"github.com/z0rr0/ipinfo/internal/testenv"
+)
+
+const (
+	testDB  = mmdbtest.DBName
+	benchIP = "193.138.218.226"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
+}
+
+func TestMMDB_Lookup(t *testing.T) {
+	if _, err := OpenMMDB("/bad_file_path.mmdb"); err == nil {
+		t.Error("unexpected behavior")
+	}
+
+	db, err := OpenMMDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	if _, err = db.Lookup(netip.MustParseAddr("127.0.0.1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	record, err := db.Lookup(netip.MustParseAddr(benchIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record.City.Names.EN != "Malmo" || record.Country.Names.EN != "Sweden" || record.Country.ISOCode != "SE" {
+		t.Errorf("unexpected record: %v", *record)
+	}
+
+	if record.Continent.Code != "EU" || record.Location.AccuracyRadius != 20 {
+		t.Errorf("unexpected continent or accuracy radius: %v", *record)
+	}
+
+	expected := SubdivisionRecord{Names: Names{EN: "Skane County", RU: "Сконе"}, ISOCode: "M"}
+	if record.Subdivision != expected {
+		t.Errorf("not equal subdivision %v != %v", record.Subdivision, expected)
+	}
+}
+
+func TestMMDB_City(t *testing.T) {
+	db, err := OpenMMDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	if _, err = db.City(netip.MustParseAddr("127.0.0.1")); err != nil {
+		t.Errorf("city error: %v", err)
+	}
+
+	city, err := db.City(netip.MustParseAddr(benchIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := city.City.Names["en"]; name != "Malmo" {
+		t.Errorf("not equal city name: %v", name)
+	}
+}
+
+// writeCompressed writes gzip compressed data to the file, it's wrapped into tar archive if name is set.
+func TestMMDB_decodedNetworks(t *testing.T) {
+	const data = `[{"cidr": "10.0.0.0/8", "data": {
+		"country": {"iso_code": "SE", "geoname_id": 2661886, "names": {"en": "Sweden"}},
+		"location": {"accuracy_radius": 20, "latitude": 55.6, "longitude": 13, "time_zone": "Europe/Stockholm"}
+	}}]`
+	networks, err := mmdbtest.DecodeNetworks(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "decoded.mmdb")
+	if err = mmdbtest.WriteFile(filename, mmdbtest.CityType, networks); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenMMDB(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	record, err := db.Lookup(netip.MustParseAddr("10.1.2.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 55.6, Longitude: 13, AccuracyRadius: 20}
+	if record.Location != expected || record.Country.ISOCode != "SE" {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	// uint16 overflow
+	networks, err = mmdbtest.DecodeNetworks(strings.NewReader(`[{"cidr": "10.0.0.0/8", "data": {"accuracy_radius": 70000}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mmdbtest.Write(io.Discard, mmdbtest.CityType, networks); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func writeCompressed(t *testing.T, filename, name string, data []byte) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+
+	if name == "" {
+		if _, err := gz.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		archive := tar.NewWriter(gz)
+		files := []struct {
+			name string
+			data []byte
+		}{
+			{name: "GeoLite2-City/LICENSE.txt", data: []byte("license")},
+			{name: name, data: data},
+		}
+		for _, f := range files {
+			header := &tar.Header{Name: f.name, Mode: 0o600, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+			if err := archive.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := archive.Write(f.data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := archive.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMMDB(t *testing.T) {
+	data, err := os.ReadFile(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"gz":       filepath.Join(dir, "city.mmdb.gz"),
+		"tar":      filepath.Join(dir, "city.tar.gz"),
+		"tgz":      filepath.Join(dir, "city.tgz"),
+		"no mmdb":  filepath.Join(dir, "empty.tar.gz"),
+		"not gzip": filepath.Join(dir, "bad.mmdb.gz"),
+	}
+	writeCompressed(t, files["gz"], "", data)
+	writeCompressed(t, files["tar"], "GeoLite2-City/GeoLite2-City.mmdb", data)
+	writeCompressed(t, files["tgz"], "GeoLite2-City.mmdb", data)
+	writeCompressed(t, files["no mmdb"], "GeoLite2-City/README.txt", data)
+
+	if err = os.WriteFile(files["not gzip"], data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		open     func(string, ...Vendor) (*MMDB, error)
+		name     string
+		filename string
+		mode     string
+	}{
+		{name: "mmap", open: OpenMMDB, filename: testDB, mode: "mmap"},
+		{name: "memory", open: LoadMMDB, filename: testDB, mode: "memory"},
+		{name: "open gz", open: OpenMMDB, filename: files["gz"], mode: "memory"},
+		{name: "load gz", open: LoadMMDB, filename: files["gz"], mode: "memory"},
+		{name: "tar", open: OpenMMDB, filename: files["tar"], mode: "memory"},
+		{name: "tgz", open: LoadMMDB, filename: files["tgz"], mode: "memory"},
+		{name: "no mmdb", open: OpenMMDB, filename: files["no mmdb"]},
+		{name: "not gzip", open: LoadMMDB, filename: files["not gzip"]},
+		{name: "not found", open: LoadMMDB, filename: "/bad_file_path.mmdb.gz"},
+	}
+	for _, c := range cases {
+		db, openErr := c.open(c.filename)
+		if c.mode == "" {
+			if openErr == nil {
+				t.Errorf("%s: expected error", c.name)
+			}
+			continue
+		}
+
+		if openErr != nil {
+			t.Errorf("%s: open error: %v", c.name, openErr)
+			continue
+		}
+
+		record, lookupErr := db.Lookup(netip.MustParseAddr(benchIP))
+		if lookupErr != nil {
+			t.Errorf("%s: lookup error: %v", c.name, lookupErr)
+		} else if record.City.Names.EN != "Malmo" {
+			t.Errorf("%s: unexpected record %v", c.name, *record)
+		}
+
+		expected := fmt.Sprintf(", %s %.1f MiB", c.mode, float64(len(data))/(1<<20))
+		if s := db.String(); !strings.HasSuffix(s, expected) {
+			t.Errorf("%s: unexpected description: %v", c.name, s)
+		}
+
+		if closeErr := db.Close(); closeErr != nil {
+			t.Errorf("%s: close error: %v", c.name, closeErr)
+		}
+	}
+}
+
+func BenchmarkMMDB_City(b *testing.B) {
+	db, err := OpenMMDB(testDB)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	addr := netip.MustParseAddr(benchIP)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err = db.City(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMMDB_Lookup(b *testing.B) {
+	db, err := OpenMMDB(testDB)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			b.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	addr := netip.MustParseAddr(benchIP)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err = db.Lookup(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 Aleksandr Zaitsev <me@axv.email>.
+// All rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the LICENSE file.
+
+package geo
+
+import (
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang/v2/mmdbdata"
+)
+
+// Languages are supported codes of localized names.
+var Languages = []string{"de", "en", "es", "fr", "ja", "ru"} //nolint:gochecknoglobals
+
+// Names contains localized names only for languages which can be selected by a country ISO code.
+// MaxMind databases also have "pt-BR" and "zh-CN" names, but they never match a lower-cased
+// two-letter ISO code, so they are skipped to avoid decoding of the full names map.
+type Names struct {
+	DE string `maxminddb:"de"`
+	EN string `maxminddb:"en"`
+	ES string `maxminddb:"es"`
+	FR string `maxminddb:"fr"`
+	JA string `maxminddb:"ja"`
+	RU string `maxminddb:"ru"`
+}
+
+// Get returns a name by language code and true if it exists.
+func (n *Names) Get(lang string) (string, bool) {
+	var name string
+
+	switch lang {
+	case "de":
+		name = n.DE
+	case "en":
+		name = n.EN
+	case "es":
+		name = n.ES
+	case "fr":
+		name = n.FR
+	case "ja":
+		name = n.JA
+	case "ru":
+		name = n.RU
+	}
+
+	return name, name != ""
+}
+
+// set sets a name by language code, unsupported languages are ignored.
+func (n *Names) set(lang, name string) {
+	switch lang {
+	case "de":
+		n.DE = name
+	case "en":
+		n.EN = name
+	case "es":
+		n.ES = name
+	case "fr":
+		n.FR = name
+	case "ja":
+		n.JA = name
+	case "ru":
+		n.RU = name
+	}
+}
+
+// CityRecord is a city part of the Record.
+type CityRecord struct {
+	Names Names `maxminddb:"names"`
+}
+
+// CountryRecord is a country part of the Record.
+type CountryRecord struct {
+	Names   Names  `maxminddb:"names"`
+	ISOCode string `maxminddb:"iso_code"`
+}
+
+// SubdivisionRecord is the first (largest) subdivision of the Record, e.g. a state or a region.
+type SubdivisionRecord struct {
+	Names   Names
+	ISOCode string
+}
+
+// UnmarshalMaxMindDB decodes only the first item of the subdivisions array.
+func (s *SubdivisionRecord) UnmarshalMaxMindDB(d *mmdbdata.Decoder) error {
+	items, _, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+
+	var decoded bool
+	for itemErr := range items {
+		if itemErr != nil {
+			return itemErr
+		}
+
+		if !decoded {
+			if err = s.decode(d); err != nil {
+				return err
+			}
+			decoded = true
+		}
+	}
+	return nil
+}
+
+func (s *SubdivisionRecord) decode(d *mmdbdata.Decoder) error {
+	fields, _, err := d.ReadMap()
+	if err != nil {
+		return err
+	}
+
+	for key, keyErr := range fields {
+		if keyErr != nil {
+			return keyErr
+		}
+
+		switch string(key) {
+		case "iso_code":
+			if s.ISOCode, err = d.ReadString(); err != nil {
+				return err
+			}
+		case "names":
+			if err = decodeNames(d, &s.Names); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeNames(d *mmdbdata.Decoder, names *Names) error {
+	items, _, err := d.ReadMap()
+	if err != nil {
+		return err
+	}
+
+	for lang, langErr := range items {
+		if langErr != nil {
+			return langErr
+		}
+
+		name, nameErr := d.ReadString()
+		if nameErr != nil {
+			return nameErr
+		}
+		names.set(string(lang), name)
+	}
+	return nil
+}
+
+// ContinentRecord is a continent part of the Record.
+type ContinentRecord struct {
+	Code string `maxminddb:"code"`
+}
+
+// LocationRecord is a location part of the Record.
+type LocationRecord struct {
+	TimeZone       string  `maxminddb:"time_zone"`
+	Latitude       float64 `maxminddb:"latitude"`
+	Longitude      float64 `maxminddb:"longitude"`
+	AccuracyRadius uint16  `maxminddb:"accuracy_radius"` // in kilometers
+}
+
+// IsEmpty returns true if the city has no names.
+func (c *CityRecord) IsEmpty() bool {
+	return c.Names == Names{}
+}
+
+// IsEmpty returns true if the subdivision has neither names nor ISO code.
+func (s *SubdivisionRecord) IsEmpty() bool {
+	return *s == SubdivisionRecord{}
+}
+
+// IsEmpty returns true if the country has neither names nor ISO code.
+func (c *CountryRecord) IsEmpty() bool {
+	return *c == CountryRecord{}
+}
+
+// IsEmpty returns true if the location has neither coordinates nor time zone.
+func (l *LocationRecord) IsEmpty() bool {
+	return *l == LocationRecord{}
+}
+
+// HasCoordinates returns true if the location has coordinates.
+func (l *LocationRecord) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// ASNRecord is an autonomous system part of the Record.
+type ASNRecord struct {
+	ASOrganization string `maxminddb:"autonomous_system_organization"`
+	ASN            uint32 `maxminddb:"autonomous_system_number"`
+}
+
+// IsEmpty returns true if the autonomous system is unknown.
+func (a *ASNRecord) IsEmpty() bool {
+	return *a == ASNRecord{}
+}
+
+// Sources are names of data sources of the record parts, they're set only by Merge locator.
+type Sources struct {
+	Country  string `json:"country,omitempty"  xml:"country,omitempty"  yaml:"country,omitempty"`
+	City     string `json:"city,omitempty"     xml:"city,omitempty"     yaml:"city,omitempty"`
+	Location string `json:"location,omitempty" xml:"location,omitempty" yaml:"location,omitempty"`
+	ASN      string `json:"asn,omitempty"      xml:"asn,omitempty"      yaml:"asn,omitempty"`
+}
+
+// Record is a compact geo record, it contains only fields used by handlers.
+// Continent is a part of the country data and subdivision is a part of the city one, so they have the same sources.
+// ASN fields are top-level ones in MaxMind ASN databases, so the part is embedded.
+type Record struct {
+	Sources     Sources           `maxminddb:"-"`
+	City        CityRecord        `maxminddb:"city"`
+	Subdivision SubdivisionRecord `maxminddb:"subdivisions"`
+	Continent   ContinentRecord   `maxminddb:"continent"`
+	Country     CountryRecord     `maxminddb:"country"`
+	ASNRecord
+	Location LocationRecord `maxminddb:"location"`
+}
+
+// Language returns a language code for the record names.
+// It's a lower-cased country ISO code if the country has a name in such language or default one.
+func (r *Record) Language() string {
+	lang := strings.ToLower(r.Country.ISOCode)
+	if _, ok := r.Country.Names.Get(lang); ok {
+		return lang
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,52 @@
+package geo
+
+import (
+	"testing"
+)
+
+func TestNames_Get(t *testing.T) {
+	names := Names{DE: "Schweden", EN: "Sweden", RU: "Швеция"}
+	cases := []struct {
+		lang     string
+		expected string
+		ok       bool
+	}{
+		{lang: "de", expected: "Schweden", ok: true},
+		{lang: "en", expected: "Sweden", ok: true},
+		{lang: "ru", expected: "Швеция", ok: true},
+		{lang: "fr"},
+		{lang: "se"},
+		{lang: ""},
+	}
+	for _, c := range cases {
+		name, ok := names.Get(c.lang)
+		if name != c.expected || ok != c.ok {
+			t.Errorf("%q: not equal %q, %v != %q, %v", c.lang, name, ok, c.expected, c.ok)
+		}
+	}
+}
+
+func TestRecord_Language(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+		record   Record
+	}{
+		{name: "empty", expected: DefaultLanguage},
+		{
+			name:     "no name",
+			record:   Record{Country: CountryRecord{ISOCode: "SE", Names: Names{EN: "Sweden"}}},
+			expected: DefaultLanguage,
+		},
+		{
+			name:     "country language",
+			record:   Record{Country: CountryRecord{ISOCode: "RU", Names: Names{EN: "Russia", RU: "Россия"}}},
+			expected: "ru",
+		},
+	}
+	for _, c := range cases {
+		if lang := c.record.Language(); lang != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.name, lang, c.expected)
+		}
+	}
+}
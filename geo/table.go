@@ -0,0 +1,64 @@
+package geo
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Table is an in-memory locator, it finds records by the longest matching network prefix.
+// Add is not safe for concurrent use, so a table should be filled before lookups.
+type Table struct {
+	items map[netip.Prefix]*Record
+	bits  []int // known prefix lengths in descending order
+}
+
+// NewTable returns new empty in-memory table.
+func NewTable() *Table {
+	return &Table{items: make(map[netip.Prefix]*Record)}
+}
+
+// Add adds or replaces a record for the network prefix.
+func (t *Table) Add(prefix netip.Prefix, record *Record) {
+	prefix = prefix.Masked()
+	t.items[prefix] = record
+
+	bits := prefix.Bits()
+	if i, found := slices.BinarySearchFunc(t.bits, bits, descending); !found {
+		t.bits = slices.Insert(t.bits, i, bits)
+	}
+}
+
+// Len returns a number of table networks.
+func (t *Table) Len() int {
+	return len(t.items)
+}
+
+// Lookup returns a record of the longest network prefix which contains the address.
+func (t *Table) Lookup(addr netip.Addr) (*Record, error) {
+	addr = addr.Unmap().WithZone("")
+
+	for _, bits := range t.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			return nil, err
+		}
+
+		if record, ok := t.items[prefix]; ok {
+			return record, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Close does nothing, it's required by Locator interface.
+func (t *Table) Close() error {
+	return nil
+}
+
+func descending(a, b int) int {
+	return b - a
+}
@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// cityTypes are substrings of City compatible database types, "location" is a part of IP2Location ones.
+var cityTypes = []string{"city", "enterprise", "location"} //nolint:gochecknoglobals
+
+// sourceTypes are substrings of database types of merged sources, they can contain only countries.
+var sourceTypes = append([]string{"country"}, cityTypes...) //nolint:gochecknoglobals
+
+// Validation is a set of database checks on startup.
+// Canaries are IP addresses with expected country ISO codes.
+type Validation struct {
+	Canaries   map[string]string `json:"canaries"`
+	MaxAgeDays int               `json:"max_age_days"`
+	Verify     bool              `json:"verify"`
+	WarnOnly   bool              `json:"warn_only"`
+}
+
+// checker is implemented by locators which can check their own data.
+type checker interface {
+	check(v *Validation, now time.Time) []error
+}
+
+// Validate checks the locator's databases and canary addresses, all found problems are joined.
+func (v *Validation) Validate(locator Locator, now time.Time) error {
+	var errs []error
+
+	if c, ok := locator.(checker); ok {
+		errs = c.check(v, now)
+	}
+
+	for ip, isoCode := range v.Canaries {
+		if err := checkCanary(locator, ip, isoCode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MMDB) check(v *Validation, now time.Time) []error {
+	return m.checkDatabase(v, now, "city", cityTypes)
+}
+
+// checkDatabase checks the database of some kind which type should contain one of types substrings.
+func (m *MMDB) checkDatabase(v *Validation, now time.Time, kind string, types []string) []error {
+	var (
+		errs     []error
+		metadata = m.reader.Metadata
+	)
+
+	if len(m.mappings) == 0 && !isType(metadata.DatabaseType, types) {
+		errs = append(errs, fmt.Errorf("database type %q is not %s compatible", metadata.DatabaseType, kind))
+	}
+
+	if v.MaxAgeDays > 0 {
+		buildTime := metadata.BuildTime()
+		if maxAge := time.Duration(v.MaxAgeDays) * 24 * time.Hour; now.Sub(buildTime) > maxAge {
+			errs = append(errs, fmt.Errorf(
+				"database %q built %s is older than %d days",
+				metadata.DatabaseType, buildTime.UTC().Format(time.DateOnly), v.MaxAgeDays,
+			))
+		}
+	}
+
+	if v.Verify {
+		if err := m.reader.Verify(); err != nil {
+			errs = append(errs, fmt.Errorf("database %q integrity: %w", metadata.DatabaseType, err))
+		}
+	}
+	return errs
+}
+
+// check checks merged sources, a source can be a country database, other parts are taken from the next ones.
+func (m Merge) check(v *Validation, now time.Time) []error {
+	var errs []error
+
+	for _, source := range m {
+		var sourceErrs []error
+
+		switch locator := source.Locator.(type) {
+		case *MMDB:
+			sourceErrs = locator.checkDatabase(v, now, "city or country", sourceTypes)
+		case checker:
+			sourceErrs = locator.check(v, now)
+		}
+
+		for _, err := range sourceErrs {
+			errs = append(errs, fmt.Errorf("source %q: %w", source.Name, err))
+		}
+	}
+	return errs
+}
+
+func (c Chain) check(v *Validation, now time.Time) []error {
+	var errs []error
+
+	for _, locator := range c {
+		if item, ok := locator.(checker); ok {
+			errs = append(errs, item.check(v, now)...)
+		}
+	}
+	return errs
+}
+
+func isType(databaseType string, types []string) bool {
+	databaseType = strings.ToLower(databaseType)
+
+	for _, t := range types {
+		if strings.Contains(databaseType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkCanary(locator Locator, ip, isoCode string) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return fmt.Errorf("canary %q: %w", ip, err)
+	}
+
+	record, err := locator.Lookup(addr)
+	if err != nil {
+		return fmt.Errorf("canary %q: %w", ip, err)
+	}
+
+	if !strings.EqualFold(record.Country.ISOCode, isoCode) {
+		return fmt.Errorf("canary %q: expected country %q, got %q", ip, isoCode, record.Country.ISOCode)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package geo
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestValidation_Validate(t *testing.T) {
+	asnName := filepath.Join(t.TempDir(), "asn.mmdb")
+	networks := []mmdbtest.Network{
+		{CIDR: "193.138.218.0/24", Data: map[string]any{"autonomous_system_number": uint32(39351)}},
+	}
+	if err := mmdbtest.WriteFile(asnName, "GeoLite2-ASN", networks); err != nil {
+		t.Fatal(err)
+	}
+
+	countryName := filepath.Join(t.TempDir(), "country.mmdb")
+	networks = []mmdbtest.Network{
+		{CIDR: "193.138.218.0/24", Data: map[string]any{"country": map[string]any{"iso_code": "SE"}}},
+	}
+	if err := mmdbtest.WriteFile(countryName, "GeoLite2-Country", networks); err != nil {
+		t.Fatal(err)
+	}
+
+	city, err := OpenMMDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asn, err := OpenMMDB(asnName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	country, err := OpenMMDB(countryName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merge := Merge{{Name: "city", Locator: city}, {Name: "country", Locator: country}, {Name: "asn", Locator: asn}}
+	defer func() {
+		if closeErr := merge.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	now := time.Now()
+	cases := []struct {
+		name       string
+		locator    Locator
+		validation Validation
+		now        time.Time
+		errors     []string
+	}{
+		{name: "empty", locator: city, now: now},
+		{
+			name:       "city",
+			locator:    city,
+			validation: Validation{Canaries: map[string]string{benchIP: "se"}, MaxAgeDays: 1, Verify: true},
+			now:        now,
+		},
+		{
+			name:       "old",
+			locator:    city,
+			validation: Validation{MaxAgeDays: 30},
+			now:        now.AddDate(0, 0, 31),
+			errors:     []string{`database "GeoLite2-City" built ` + now.UTC().Format(time.DateOnly) + " is older than 30 days"},
+		},
+		{
+			name:       "canaries",
+			locator:    city,
+			validation: Validation{Canaries: map[string]string{benchIP: "NO", "127.0.0.1": "US", "bad": "US"}},
+			now:        now,
+			errors: []string{
+				`canary "193.138.218.226": expected country "NO", got "SE"`,
+				`canary "127.0.0.1": record not found`,
+				`canary "bad": ParseAddr("bad"): unable to parse IP`,
+			},
+		},
+		{
+			name:    "asn",
+			locator: asn,
+			now:     now,
+			errors:  []string{`database type "GeoLite2-ASN" is not city compatible`},
+		},
+		{
+			name:       "merge",
+			locator:    merge,
+			validation: Validation{Canaries: map[string]string{benchIP: "SE"}},
+			now:        now,
+			errors:     []string{`source "asn": database type "GeoLite2-ASN" is not city or country compatible`},
+		},
+		{
+			name:    "country",
+			locator: country,
+			now:     now,
+			errors:  []string{`database type "GeoLite2-Country" is not city compatible`},
+		},
+		{
+			name:    "chain",
+			locator: Chain{city, asn, NewTable()},
+			now:     now,
+			errors:  []string{`database type "GeoLite2-ASN" is not city compatible`},
+		},
+	}
+	for _, c := range cases {
+		err = c.validation.Validate(c.locator, c.now)
+		if len(c.errors) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("%s: expected error", c.name)
+			continue
+		}
+
+		for _, e := range c.errors {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("%s: not found %q in %q", c.name, e, err.Error())
+			}
+		}
+
+		if n := len(strings.Split(err.Error(), "\n")); n != len(c.errors) {
+			t.Errorf("%s: unexpected number of errors %d", c.name, n)
+		}
+	}
+
+	if err = (&Validation{Canaries: map[string]string{benchIP: "SE"}}).Validate(errLocator{err: errors.New("failed")}, now); err == nil {
+		t.Error("expected error for failed locator")
+	}
+}
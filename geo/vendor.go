@@ -0,0 +1,260 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Vendor describes a mmdb provider schema.
+// DatabaseType is a case-insensitive path.Match pattern of the metadata database type.
+// Fields keys are Record fields in MaxMind City notation, e.g. "country.iso_code",
+// and values are dot-separated paths of the vendor's data, e.g. "country_code".
+// A vendor without fields has MaxMind City compatible schema.
+type Vendor struct {
+	Fields       map[string]string `json:"fields"`
+	Name         string            `json:"name"`
+	DatabaseType string            `json:"database_type"`
+}
+
+// BuiltinVendors are known mmdb providers, they're used after custom ones.
+var BuiltinVendors = []Vendor{ //nolint:gochecknoglobals
+	{Name: "MaxMind", DatabaseType: "geoip2-*"},
+	{Name: "MaxMind", DatabaseType: "geolite2-*"},
+	{Name: "DB-IP", DatabaseType: "dbip-*"},
+	{Name: "IP2Location", DatabaseType: "ip2location*"},
+	{
+		Name:         "IPinfo",
+		DatabaseType: "ipinfo*location*",
+		Fields: map[string]string{
+			"country.iso_code":   "country",
+			"country.names.en":   "country_name",
+			"city.names.en":      "city",
+			"location.time_zone": "timezone",
+			"location.latitude":  "latitude",
+			"location.longitude": "longitude",
+		},
+	},
+	{
+		Name:         "IPinfo",
+		DatabaseType: "ipinfo*",
+		Fields: map[string]string{
+			"continent.code":                 "continent_code",
+			"country.iso_code":               "country_code",
+			"country.names.en":               "country",
+			"autonomous_system_number":       "asn",
+			"autonomous_system_organization": "as_name",
+		},
+	},
+}
+
+// fieldSetter sets a Record field by decoded mmdb value.
+type fieldSetter func(r *Record, value any) error
+
+// fieldMapping is a prepared vendor field.
+type fieldMapping struct {
+	set  fieldSetter
+	path []any
+}
+
+// Match returns true if the vendor schema is used for the database type.
+func (v *Vendor) Match(databaseType string) bool {
+	matched, err := path.Match(strings.ToLower(v.DatabaseType), strings.ToLower(databaseType))
+	return err == nil && matched
+}
+
+// Validate checks the vendor's settings.
+func (v *Vendor) Validate() error {
+	if v.DatabaseType == "" {
+		return fmt.Errorf("vendor %q: empty database type", v.Name)
+	}
+
+	if _, err := path.Match(v.DatabaseType, ""); err != nil {
+		return fmt.Errorf("vendor %q database type: %w", v.Name, err)
+	}
+	_, err := v.mappings()
+	return err
+}
+
+// String returns vendor name.
+func (v *Vendor) String() string {
+	if v.Name == "" {
+		return v.DatabaseType
+	}
+	return v.Name
+}
+
+// mappings returns prepared fields in stable order.
+func (v *Vendor) mappings() ([]fieldMapping, error) {
+	targets := make([]string, 0, len(v.Fields))
+	for target := range v.Fields {
+		targets = append(targets, target)
+	}
+	slices.Sort(targets)
+
+	result := make([]fieldMapping, 0, len(targets))
+	for _, target := range targets {
+		setter, err := recordSetter(target)
+		if err != nil {
+			return nil, fmt.Errorf("vendor %q: %w", v.Name, err)
+		}
+
+		source := v.Fields[target]
+		if source == "" {
+			return nil, fmt.Errorf("vendor %q: empty path for %q", v.Name, target)
+		}
+
+		keys := strings.Split(source, ".")
+		fieldPath := make([]any, len(keys))
+		for i, key := range keys {
+			if index, indexErr := strconv.Atoi(key); indexErr == nil {
+				fieldPath[i] = index // array item
+			} else {
+				fieldPath[i] = key
+			}
+		}
+		result = append(result, fieldMapping{set: setter, path: fieldPath})
+	}
+	return result, nil
+}
+
+// findVendor returns the first vendor which matches the database type.
+func findVendor(databaseType string, vendors []Vendor) (*Vendor, bool) {
+	for _, group := range [][]Vendor{vendors, BuiltinVendors} {
+		for i := range group {
+			if group[i].Match(databaseType) {
+				return &group[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// recordSetter returns a setter for the Record field in MaxMind City notation.
+func recordSetter(target string) (fieldSetter, error) {
+	switch target {
+	case "continent.code":
+		return func(r *Record, value any) error { return setString(&r.Continent.Code, value) }, nil
+	case "country.iso_code":
+		return func(r *Record, value any) error { return setString(&r.Country.ISOCode, value) }, nil
+	case "location.time_zone":
+		return func(r *Record, value any) error { return setString(&r.Location.TimeZone, value) }, nil
+	case "location.latitude":
+		return func(r *Record, value any) error { return setFloat(&r.Location.Latitude, value) }, nil
+	case "location.longitude":
+		return func(r *Record, value any) error { return setFloat(&r.Location.Longitude, value) }, nil
+	case "location.accuracy_radius":
+		return func(r *Record, value any) error { return setRadius(&r.Location.AccuracyRadius, value) }, nil
+	case "subdivisions.0.iso_code":
+		return func(r *Record, value any) error { return setString(&r.Subdivision.ISOCode, value) }, nil
+	case "autonomous_system_number":
+		return func(r *Record, value any) error { return setASN(&r.ASN, value) }, nil
+	case "autonomous_system_organization":
+		return func(r *Record, value any) error { return setString(&r.ASOrganization, value) }, nil
+	}
+
+	if lang, ok := strings.CutPrefix(target, "country.names."); ok {
+		return namesSetter(lang, func(r *Record) *Names { return &r.Country.Names })
+	}
+
+	if lang, ok := strings.CutPrefix(target, "city.names."); ok {
+		return namesSetter(lang, func(r *Record) *Names { return &r.City.Names })
+	}
+
+	if lang, ok := strings.CutPrefix(target, "subdivisions.0.names."); ok {
+		return namesSetter(lang, func(r *Record) *Names { return &r.Subdivision.Names })
+	}
+
+	return nil, fmt.Errorf("unknown field %q", target)
+}
+
+func namesSetter(lang string, names func(r *Record) *Names) (fieldSetter, error) {
+	if !slices.Contains(Languages, lang) {
+		return nil, fmt.Errorf("unsupported names language %q", lang)
+	}
+
+	return func(r *Record, value any) error {
+		var name string
+		if err := setString(&name, value); err != nil {
+			return err
+		}
+		names(r).set(lang, name)
+		return nil
+	}, nil
+}
+
+func setString(field *string, value any) error {
+	switch v := value.(type) {
+	case string:
+		*field = v
+	case float64, float32, int, int32, uint16, uint32, uint64:
+		*field = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("unexpected string value type %T", value)
+	}
+	return nil
+}
+
+func setFloat(field *float64, value any) error {
+	var err error
+
+	switch v := value.(type) {
+	case float64:
+		*field = v
+	case float32:
+		*field = float64(v)
+	case int:
+		*field = float64(v)
+	case int32:
+		*field = float64(v)
+	case uint16:
+		*field = float64(v)
+	case uint32:
+		*field = float64(v)
+	case uint64:
+		*field = float64(v)
+	case string:
+		*field, err = strconv.ParseFloat(v, 64)
+	default:
+		err = fmt.Errorf("unexpected number value type %T", value)
+	}
+	return err
+}
+
+func setRadius(field *uint16, value any) error {
+	var radius float64
+
+	if err := setFloat(&radius, value); err != nil {
+		return err
+	}
+
+	if radius < 0 || radius > math.MaxUint16 {
+		return fmt.Errorf("accuracy radius %v is out of range", radius)
+	}
+
+	*field = uint16(radius)
+	return nil
+}
+
+// setASN sets autonomous system number, string values can have "AS" prefix.
+func setASN(field *uint32, value any) error {
+	var number float64
+
+	if s, ok := value.(string); ok {
+		value = strings.TrimPrefix(strings.ToUpper(s), "AS")
+	}
+
+	if err := setFloat(&number, value); err != nil {
+		return err
+	}
+
+	if number < 0 || number > math.MaxUint32 {
+		return fmt.Errorf("autonomous system number %v is out of range", number)
+	}
+
+	*field = uint32(number)
+	return nil
+}
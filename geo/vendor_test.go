@@ -0,0 +1,215 @@
+package geo
+
+import (
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestVendor_Match(t *testing.T) {
+	cases := []struct {
+		databaseType string
+		expected     string
+	}{
+		{databaseType: "GeoLite2-City", expected: "MaxMind"},
+		{databaseType: "GeoIP2-City", expected: "MaxMind"},
+		{databaseType: "DBIP-City-Lite", expected: "DB-IP"},
+		{databaseType: "IP2LOCATION-LITE-DB11", expected: "IP2Location"},
+		{databaseType: "ipinfo standard_location.mmdb", expected: "IPinfo"},
+		{databaseType: "ipinfo lite.mmdb", expected: "IPinfo"},
+		{databaseType: "custom-db"},
+	}
+	for _, c := range cases {
+		vendor, ok := findVendor(c.databaseType, nil)
+		if c.expected == "" {
+			if ok {
+				t.Errorf("%s: unexpected vendor %v", c.databaseType, vendor)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("%s: vendor not found", c.databaseType)
+			continue
+		}
+
+		if name := vendor.String(); name != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.databaseType, name, c.expected)
+		}
+	}
+
+	custom := []Vendor{{DatabaseType: "Custom-*"}, {Name: "Lite", DatabaseType: "ipinfo lite*"}}
+	if vendor, ok := findVendor("custom-db", custom); !ok || vendor.String() != "Custom-*" {
+		t.Errorf("unexpected custom vendor: %v", vendor)
+	}
+
+	if vendor, ok := findVendor("ipinfo lite.mmdb", custom); !ok || vendor.String() != "Lite" {
+		t.Errorf("custom vendor is not preferred: %v", vendor)
+	}
+}
+
+func TestVendor_Validate(t *testing.T) {
+	for i := range BuiltinVendors {
+		if err := BuiltinVendors[i].Validate(); err != nil {
+			t.Errorf("builtin vendor %v: %v", BuiltinVendors[i].String(), err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		vendor Vendor
+	}{
+		{name: "empty database type", vendor: Vendor{Name: "test"}},
+		{name: "bad pattern", vendor: Vendor{DatabaseType: "["}},
+		{name: "unknown field", vendor: Vendor{DatabaseType: "test", Fields: map[string]string{"city.geoname_id": "id"}}},
+		{name: "unknown language", vendor: Vendor{DatabaseType: "test", Fields: map[string]string{"city.names.zh-CN": "city"}}},
+		{name: "empty path", vendor: Vendor{DatabaseType: "test", Fields: map[string]string{"city.names.en": ""}}},
+	}
+	for _, c := range cases {
+		if err := c.vendor.Validate(); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
+
+func TestVendor_mappings(t *testing.T) {
+	vendor := Vendor{
+		Name: "test",
+		Fields: map[string]string{
+			"country.iso_code":         "country_code",
+			"country.names.ru":         "names.1",
+			"city.names.en":            "city",
+			"location.time_zone":       "tz",
+			"location.latitude":        "lat",
+			"location.longitude":       "lon",
+			"location.accuracy_radius": "radius",
+		},
+	}
+	mappings, err := vendor.mappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]any{
+		"city":         "Malmo",
+		"country_code": "SE",
+		"names.1":      "Швеция",
+		"tz":           "Europe/Stockholm",
+		"lat":          "55.6078",
+		"lon":          float32(12.5),
+		"radius":       uint16(20),
+	}
+	// mappings are sorted by target field
+	sources := []string{"city", "country_code", "names.1", "radius", "lat", "lon", "tz"}
+	if len(mappings) != len(sources) {
+		t.Fatalf("unexpected mappings length %d", len(mappings))
+	}
+
+	record := &Record{}
+	for i, mapping := range mappings {
+		if err = mapping.set(record, values[sources[i]]); err != nil {
+			t.Errorf("%v: set error: %v", mapping.path, err)
+		}
+	}
+
+	expected := Record{
+		City:     CityRecord{Names: Names{EN: "Malmo"}},
+		Country:  CountryRecord{Names: Names{RU: "Швеция"}, ISOCode: "SE"},
+		Location: LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 55.6078, Longitude: 12.5, AccuracyRadius: 20},
+	}
+	if *record != expected {
+		t.Errorf("not equal %v != %v", *record, expected)
+	}
+
+	if p := mappings[2].path; len(p) != 2 || p[0] != "names" || p[1] != 1 {
+		t.Errorf("unexpected path %v", p)
+	}
+
+	if err = mappings[3].set(record, -1); err == nil {
+		t.Error("expected accuracy radius error")
+	}
+
+	if err = mappings[4].set(record, "north"); err == nil {
+		t.Error("expected latitude error")
+	}
+
+	if err = mappings[0].set(record, []any{"Malmo"}); err == nil {
+		t.Error("expected city error")
+	}
+}
+
+func TestOpenMMDB_vendors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "location.mmdb")
+	networks := []mmdbtest.Network{
+		{
+			CIDR: "193.138.218.0/24",
+			Data: map[string]any{
+				"city":         "Malmö",
+				"country":      "SE",
+				"country_name": "Sweden",
+				"region":       "Skåne",
+				"timezone":     "Europe/Stockholm",
+				"latitude":     "55.60587",
+				"longitude":    "13.00073",
+				"postal_code":  "200 01",
+			},
+		},
+	}
+	if err := mmdbtest.WriteFile(filename, "ipinfo standard_location.mmdb", networks); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		vendor   string
+		vendors  []Vendor
+		expected Record
+	}{
+		{
+			name:   "builtin",
+			vendor: "IPinfo",
+			expected: Record{
+				City:     CityRecord{Names: Names{EN: "Malmö"}},
+				Country:  CountryRecord{ISOCode: "SE", Names: Names{EN: "Sweden"}},
+				Location: LocationRecord{TimeZone: "Europe/Stockholm", Latitude: 55.60587, Longitude: 13.00073},
+			},
+		},
+		{
+			name:    "custom",
+			vendor:  "Custom",
+			vendors: []Vendor{{Name: "Custom", DatabaseType: "ipinfo*", Fields: map[string]string{"city.names.en": "region"}}},
+			expected: Record{
+				City: CityRecord{Names: Names{EN: "Skåne"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		db, err := OpenMMDB(filename, c.vendors...)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		record, err := db.Lookup(netip.MustParseAddr("193.138.218.226"))
+		if err != nil {
+			t.Errorf("%s: lookup error: %v", c.name, err)
+		} else if *record != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.name, *record, c.expected)
+		}
+
+		if _, err = db.Lookup(netip.MustParseAddr("127.0.0.1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected not found error, got %v", c.name, err)
+		}
+
+		if s := db.String(); !strings.HasPrefix(s, "mmdb: "+c.vendor+" ipinfo standard_location.mmdb, built ") {
+			t.Errorf("%s: unexpected description %v", c.name, s)
+		}
+
+		if err = db.Close(); err != nil {
+			t.Errorf("%s: close error: %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handle
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"unicode"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// AuthPath is URL path of AuthHandler, it's not restricted by GeoFence.
+const AuthPath = "/auth"
+
+// GeoHeaders returns enrichment headers with client's geo data, empty values are skipped.
+// Non-ASCII values are percent-encoded.
+func GeoHeaders(info *conf.IPInfo) http.Header {
+	values := []struct {
+		name  string
+		value string
+	}{
+		{name: "X-Geo-IP", value: info.IP},
+		{name: "X-Geo-Country", value: info.CountryCode},
+		{name: "X-Geo-Country-Name", value: info.Country},
+		{name: "X-Geo-Continent", value: info.Continent},
+		{name: "X-Geo-Subdivision", value: info.SubdivisionCode},
+		{name: "X-Geo-City", value: info.City},
+		{name: "X-Geo-Time-Zone", value: info.TimeZone},
+		{name: "X-Geo-AS-Organization", value: info.ASOrganization},
+	}
+
+	headers := make(http.Header, len(values)+3)
+	for _, v := range values {
+		if v.value != "" {
+			headers.Set(v.name, headerValue(v.value))
+		}
+	}
+
+	if info.ASN != 0 {
+		headers.Set("X-Geo-ASN", strconv.FormatUint(uint64(info.ASN), 10))
+	}
+
+	if info.HasCoordinates() {
+		headers.Set("X-Geo-Latitude", strconv.FormatFloat(info.Latitude, 'f', -1, 64))
+		headers.Set("X-Geo-Longitude", strconv.FormatFloat(info.Longitude, 'f', -1, 64))
+	}
+	return headers
+}
+
+// AuthHandler is handler for reverse proxies' external authentication requests,
+// like nginx auth_request or Traefik ForwardAuth. It checks the client by auth policy
+// and returns 200 or 403 status code, both responses have geo enrichment headers.
+func AuthHandler(w http.ResponseWriter, _ *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+	for name, values := range GeoHeaders(info) {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if !cfg.Auth.Allowed(info) {
+		w.WriteHeader(http.StatusForbidden)
+		return printF(nil, w, "forbidden\n")
+	}
+	return printF(nil, w, "ok\n")
+}
+
+// headerValue returns the value as is if it contains only printable ASCII characters or percent-encoded one.
+func headerValue(value string) string {
+	for _, r := range value {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return url.PathEscape(value)
+		}
+	}
+	return value
+}
@@ -0,0 +1,108 @@
+package handle
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestAuthHandler(t *testing.T) {
+	const configName = "auth.json"
+	config := `{
+		"ip_header": "X-Forwarded-For",
+		"db": "` + mmdbtest.DBName + `",
+		"asn_db": "` + mmdbtest.ASNDBName + `",
+		"auth": {
+			"allow": {"continents": ["EU"], "cidrs": ["10.0.0.0/8"], "asns": [13335]},
+			"deny": {"countries": ["RU"], "asns": [20712]}
+		}
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := conf.New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cases := []struct {
+		headers map[string]string
+		ip      string
+		code    int
+	}{
+		{
+			ip:   "5.255.255.5, 193.138.218.226",
+			code: http.StatusOK,
+			headers: map[string]string{
+				"X-Geo-IP":              "193.138.218.226",
+				"X-Geo-Country":         "SE",
+				"X-Geo-Country-Name":    "Sweden",
+				"X-Geo-Continent":       "EU",
+				"X-Geo-Subdivision":     "SE-M",
+				"X-Geo-City":            "Malmo",
+				"X-Geo-Time-Zone":       "Europe/Stockholm",
+				"X-Geo-ASN":             "39351",
+				"X-Geo-AS-Organization": "31173 Services AB",
+				"X-Geo-Latitude":        "55.6078",
+				"X-Geo-Longitude":       "12.9982",
+			},
+		},
+		{
+			ip:      "193.138.218.226, 5.255.255.5",
+			code:    http.StatusForbidden,
+			headers: map[string]string{"X-Geo-Country": "RU", "X-Geo-City": "%D0%9C%D0%BE%D1%81%D0%BA%D0%B2%D0%B0"},
+		},
+		{ip: "81.2.69.1", code: http.StatusForbidden, headers: map[string]string{"X-Geo-ASN": "20712"}},
+		{ip: "1.1.1.1", code: http.StatusOK, headers: map[string]string{"X-Geo-ASN": "13335", "X-Geo-Country": ""}},
+		{ip: "10.1.2.3", code: http.StatusOK, headers: map[string]string{"X-Geo-IP": "10.1.2.3", "X-Geo-Latitude": ""}},
+		{ip: "2001:218::1", code: http.StatusForbidden, headers: map[string]string{"X-Geo-Continent": "AS"}},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/auth", nil)
+		req.Header.Set("X-Forwarded-For", c.ip)
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w := httptest.NewRecorder()
+		if err = AuthHandler(w, req, cfg, info); err != nil {
+			t.Errorf("%s: unexpected error: %v", c.ip, err)
+			continue
+		}
+
+		resp := w.Result()
+		checkNoCache(t, resp)
+
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: not equal status code %d != %d", c.ip, resp.StatusCode, c.code)
+		}
+
+		for name, value := range c.headers {
+			if v := resp.Header.Get(name); v != value {
+				t.Errorf("%s: not equal header %s %q != %q", c.ip, name, v, value)
+			}
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		if expected := map[int]string{http.StatusOK: "ok\n", http.StatusForbidden: "forbidden\n"}[c.code]; string(body) != expected {
+			t.Errorf("%s: not equal body %q != %q", c.ip, body, expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+	"unicode"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// maxBatchSize is a maximum number of addresses of a batch lookup.
+const maxBatchSize = 1000
+
+// batchFormat writes infos of a batch lookup, the request is used for format parameters.
+// Only selected fields are written if they're not nil.
+type batchFormat struct {
+	write       func(w io.Writer, r *http.Request, fields []infoField, infos []*conf.IPInfo) error
+	contentType string
+}
+
+// batchFormats are formats of batch lookups by name of "format" parameter.
+var batchFormats = map[string]batchFormat{ //nolint:gochecknoglobals
+	"json": {
+		contentType: "application/json; charset=utf-8",
+		write: func(w io.Writer, _ *http.Request, fields []infoField, infos []*conf.IPInfo) error {
+			if fields == nil {
+				return json.NewEncoder(w).Encode(infos)
+			}
+			return encodeBatchFields(w, fields, infos)
+		},
+	},
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		write: func(w io.Writer, _ *http.Request, fields []infoField, infos []*conf.IPInfo) error {
+			return writeCSV(w, fields, infos...)
+		},
+	},
+	"geojson": {
+		contentType: "application/geo+json",
+		write: func(w io.Writer, r *http.Request, fields []infoField, infos []*conf.IPInfo) error {
+			circle, err := circleParam(r)
+			if err != nil {
+				return err
+			}
+			return json.NewEncoder(w).Encode(newGeoJSONFeatureCollection(infos, circle, fields))
+		},
+	},
+	"kml": {
+		contentType: "application/vnd.google-earth.kml+xml; charset=utf-8",
+		write: func(w io.Writer, r *http.Request, fields []infoField, infos []*conf.IPInfo) error {
+			circle, err := circleParam(r)
+			if err != nil {
+				return err
+			}
+			return writeKML(w, circle, fields, infos...)
+		},
+	},
+}
+
+// batchOffers are negotiated batchFormats, JSON is the default one.
+var batchOffers = []mediaOffer{ //nolint:gochecknoglobals
+	{mediaType: "application/json", format: "json"},
+	{mediaType: "text/csv", format: "csv"},
+	{mediaType: "application/geo+json", format: "geojson"},
+	{mediaType: "application/vnd.google-earth.kml+xml", format: "kml"},
+}
+
+// BatchHandler is handler of lookups of several IP addresses, they are set by "ip" parameters
+// of the query or the form, a value can contain comma or space separated addresses.
+// The response format is set by "format" parameter or negotiated by Accept header, JSON array is the default one.
+// Items of all formats have only attributes of "fields" parameter if it's set.
+func BatchHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, _ *conf.IPInfo) error {
+	name := r.FormValue("format")
+	if name == "" {
+		offer, err := acceptOffer(w, r, batchOffers)
+		if err != nil {
+			return err
+		}
+		name = offer.format
+	}
+
+	format, ok := batchFormats[name]
+	if !ok {
+		return &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("unknown format %q", name)}
+	}
+
+	fields, err := fieldsParam(r)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := batchAddresses(r)
+	if err != nil {
+		return err
+	}
+
+	infos := make([]*conf.IPInfo, len(addresses))
+	for i, address := range addresses {
+		if infos[i], err = cfg.LookupInfo(address); err != nil {
+			return fmt.Errorf("BatchHandler: lookup %s: %w", address, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if err = format.write(w, r, fields, infos); err != nil {
+		return fmt.Errorf("BatchHandler: %w", err)
+	}
+	return nil
+}
+
+// encodeBatchFields writes JSON array of objects with selected fields.
+func encodeBatchFields(w io.Writer, fields []infoField, infos []*conf.IPInfo) error {
+	items := make([]json.RawMessage, len(infos))
+
+	for i, info := range infos {
+		item, err := marshalFieldsJSON(selectFields(fields, info))
+		if err != nil {
+			return err
+		}
+		items[i] = item
+	}
+	return json.NewEncoder(w).Encode(items)
+}
+
+// batchAddresses returns addresses of "ip" parameters in the request order, all of them are valid IP addresses.
+func batchAddresses(r *http.Request) ([]string, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	isSeparator := func(c rune) bool { return c == ',' || unicode.IsSpace(c) }
+	var addresses []string
+
+	for _, value := range r.Form["ip"] {
+		addresses = append(addresses, strings.FieldsFunc(value, isSeparator)...)
+	}
+
+	switch {
+	case len(addresses) == 0:
+		return nil, &StatusError{Code: http.StatusBadRequest, Err: errors.New("no ip parameters")}
+	case len(addresses) > maxBatchSize:
+		return nil, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("too many addresses, maximum is %d", maxBatchSize)}
+	}
+
+	for _, address := range addresses {
+		if _, err := netip.ParseAddr(address); err != nil {
+			return nil, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid IP address %q", address)}
+		}
+	}
+	return addresses, nil
+}
@@ -0,0 +1,298 @@
+package handle
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestBatchHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	cases := []struct {
+		name   string
+		query  string
+		form   url.Values
+		ips    []string
+		cities []string
+		code   int
+	}{
+		{
+			name:   "json",
+			query:  "ip=193.138.218.226,127.0.0.1&ip=81.2.69.1",
+			ips:    []string{"193.138.218.226", "127.0.0.1", "81.2.69.1"},
+			cities: []string{"Malmo", "", "London"},
+		},
+		{
+			name:   "csv",
+			query:  "format=csv&ip=81.2.69.1+193.138.218.226",
+			ips:    []string{"81.2.69.1", "193.138.218.226"},
+			cities: []string{"London", "Malmo"},
+		},
+		{
+			name:   "form",
+			form:   url.Values{"ip": {"193.138.218.226\n81.2.69.1"}, "format": {"csv"}},
+			ips:    []string{"193.138.218.226", "81.2.69.1"},
+			cities: []string{"Malmo", "London"},
+		},
+		{name: "no addresses", query: "ip=,", code: http.StatusBadRequest},
+		{name: "invalid address", query: "ip=1.1.1.1,bad", code: http.StatusBadRequest},
+		{name: "unknown format", query: "ip=1.1.1.1&format=xml", code: http.StatusBadRequest},
+		{name: "too many", query: "ip=" + strings.Repeat("1.1.1.1,", maxBatchSize+1), code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/batch?"+c.query, nil)
+		if c.form != nil {
+			req = httptest.NewRequest("POST", "https://example.com/batch", strings.NewReader(c.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		w := httptest.NewRecorder()
+		err := BatchHandler(w, req, cfg, nil)
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		resp := w.Result()
+		checkNoCache(t, resp)
+
+		var ips, cities []string
+		if c.name == "json" {
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+			}
+
+			var infos []conf.IPInfo
+			if err = json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, info := range infos {
+				ips, cities = append(ips, info.IP), append(cities, info.City)
+			}
+		} else {
+			if ct := resp.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+				t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+			}
+
+			rows, csvErr := csv.NewReader(resp.Body).ReadAll()
+			if csvErr != nil {
+				t.Fatal(csvErr)
+			}
+
+			if len(rows) == 0 || rows[0][0] != "ip" || rows[0][6] != "city" {
+				t.Fatalf("%s: unexpected header %v", c.name, rows)
+			}
+
+			for _, row := range rows[1:] {
+				ips, cities = append(ips, row[0]), append(cities, row[6])
+			}
+		}
+
+		if strings.Join(ips, " ") != strings.Join(c.ips, " ") || strings.Join(cities, " ") != strings.Join(c.cities, " ") {
+			t.Errorf("%s: not equal %v %v != %v %v", c.name, ips, cities, c.ips, c.cities)
+		}
+	}
+}
+
+func TestBatchHandler_lookupError(t *testing.T) {
+	cfg, err := conf.New(testConfigName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cfg.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "https://example.com/batch?ip=193.138.218.226", nil)
+	err = BatchHandler(httptest.NewRecorder(), req, cfg, nil)
+
+	var statusErr *StatusError
+	if err == nil || errors.As(err, &statusErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchHandler_maps(t *testing.T) {
+	cfg := newTestCfg(t)
+	const query = "ip=193.138.218.226,127.0.0.1&circle=true&format="
+
+	req := httptest.NewRequest("GET", "https://example.com/batch?"+query+"geojson", nil)
+	w := httptest.NewRecorder()
+
+	if err := BatchHandler(w, req, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := w.Result().Header.Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+
+	var collection GeoJSONFeatureCollection
+	if err := json.Unmarshal(w.Body.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(collection.Features); collection.Type != "FeatureCollection" || n != 2 {
+		t.Fatalf("unexpected collection %s %d", collection.Type, n)
+	}
+
+	features := collection.Features
+	if g := features[0].Geometry; g == nil || g.Type != "GeometryCollection" || features[1].Geometry != nil {
+		t.Errorf("unexpected geometries %+v %+v", features[0].Geometry, features[1].Geometry)
+	}
+
+	if features[0].Properties.IP != "193.138.218.226" || features[1].Properties.IP != "127.0.0.1" {
+		t.Errorf("unexpected properties %+v %+v", features[0].Properties, features[1].Properties)
+	}
+
+	req = httptest.NewRequest("GET", "https://example.com/batch?"+query+"kml", nil)
+	w = httptest.NewRecorder()
+
+	if err := BatchHandler(w, req, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := w.Result().Header.Get("Content-Type"); ct != "application/vnd.google-earth.kml+xml; charset=utf-8" {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+
+	doc := &kmlDocument{}
+	if err := xml.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	placemarks := doc.Placemarks
+	if len(placemarks) != 2 || placemarks[0].Multi == nil || placemarks[1].Name != "127.0.0.1" || placemarks[1].Point != nil {
+		t.Errorf("unexpected placemarks %+v", placemarks)
+	}
+
+	req = httptest.NewRequest("GET", "https://example.com/batch?ip=1.1.1.1&format=kml&circle=bad", nil)
+	var statusErr *StatusError
+
+	if err := BatchHandler(httptest.NewRecorder(), req, cfg, nil); !errors.As(err, &statusErr) || statusErr.Code != http.StatusBadRequest {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchHandler_fields(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	cases := []struct {
+		format   string
+		fields   string
+		expected string
+		code     int
+	}{
+		{
+			format:   "json",
+			expected: `[{"ip":"193.138.218.226","city":"Malmo"},{"ip":"127.0.0.1","city":""}]` + "\n",
+		},
+		{
+			format:   "csv",
+			expected: "ip,city\n193.138.218.226,Malmo\n127.0.0.1,\n",
+		},
+		{
+			format:   "geojson",
+			expected: `"properties":{"ip":"127.0.0.1","city":""}`,
+		},
+		{
+			format:   "kml",
+			expected: `<ExtendedData>` + "\n" + `        <Data name="ip">` + "\n" + `          <value>127.0.0.1</value>`,
+		},
+		{format: "json", fields: "ip,bad", code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if c.fields == "" {
+			c.fields = "ip,city"
+		}
+
+		query := "ip=193.138.218.226,127.0.0.1&fields=" + c.fields + "&format=" + c.format
+		req := httptest.NewRequest("GET", "https://example.com/batch?"+query, nil)
+		w := httptest.NewRecorder()
+		err := BatchHandler(w, req, cfg, nil)
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: unexpected error: %v", c.format, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", c.format, err)
+		}
+
+		if body := w.Body.String(); !strings.Contains(body, c.expected) || strings.Contains(body, "country") {
+			t.Errorf("%s: unexpected body %s", c.format, body)
+		}
+	}
+}
+
+func TestBatchHandler_accept(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	cases := []struct {
+		accept      string
+		query       string
+		contentType string
+		code        int
+	}{
+		{contentType: "application/json; charset=utf-8"},
+		{accept: "*/*", contentType: "application/json; charset=utf-8"},
+		{accept: "text/csv", contentType: "text/csv; charset=utf-8"},
+		{accept: "text/html, application/geo+json;q=0.5, */*;q=0.1", contentType: "application/geo+json"},
+		{accept: "application/vnd.google-earth.kml+xml", contentType: "application/vnd.google-earth.kml+xml; charset=utf-8"},
+		{accept: "text/csv", query: "&format=json", contentType: "application/json; charset=utf-8"},
+		{accept: "text/html", code: http.StatusNotAcceptable},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/batch?ip=193.138.218.226"+c.query, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+
+		w := httptest.NewRecorder()
+		err := BatchHandler(w, req, cfg, nil)
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%q: unexpected error: %v", c.accept, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%q: %v", c.accept, err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%q: not equal Content-Type: %v", c.accept, ct)
+		}
+
+		if vary := resp.Header.Get("Vary"); c.query == "" && vary != "Accept" {
+			t.Errorf("%q: not equal Vary: %q", c.accept, vary)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package handle
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+// protoSchema is protobuf schema of ProtobufHandler responses.
+//
+//go:embed ipinfo.proto
+var protoSchema string
+
+// MsgPackHandler is handler for application/msgpack response, its keys are the same as JSON ones.
+func MsgPackHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+
+	if err := encoder.Encode(info); err != nil {
+		return fmt.Errorf("MsgPackHandler: %w", err)
+	}
+	return nil
+}
+
+// CBORHandler is handler for application/cbor response, its keys are the same as JSON ones.
+func CBORHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "application/cbor")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if err := cbor.NewEncoder(w).Encode(info); err != nil {
+		return fmt.Errorf("CBORHandler: %w", err)
+	}
+	return nil
+}
+
+// ProtobufHandler is handler for application/x-protobuf response, its message is IPInfo of protoSchema.
+func ProtobufHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if _, err := w.Write(marshalProto(info)); err != nil {
+		return fmt.Errorf("ProtobufHandler: %w", err)
+	}
+	return nil
+}
+
+// ProtoSchemaHandler is handler for protobuf schema response.
+func ProtoSchemaHandler(w http.ResponseWriter, _ *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return printF(nil, w, "%s", protoSchema)
+}
+
+// marshalProto encodes info as IPInfo message of protoSchema, zero values are omitted like proto3 does.
+func marshalProto(info *conf.IPInfo) []byte {
+	var b []byte
+
+	for _, field := range infoFields {
+		b = appendProtoValue(b, field.protoNum, field.value(info))
+	}
+
+	if info.Sources != nil {
+		b = protowire.AppendTag(b, 15, protowire.BytesType)
+		b = protowire.AppendBytes(b, marshalProtoSources(info.Sources))
+	}
+	return b
+}
+
+// appendProtoValue appends a field value by its type, it's a scalar type of protoSchema.
+func appendProtoValue(b []byte, num protowire.Number, value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return appendProtoString(b, num, v)
+	case uint32:
+		return appendProtoVarint(b, num, uint64(v))
+	case uint16:
+		return appendProtoVarint(b, num, uint64(v))
+	case int:
+		return appendProtoVarint(b, num, protowire.EncodeZigZag(int64(v)))
+	case float64:
+		return appendProtoDouble(b, num, v)
+	case bool:
+		if v {
+			return appendProtoVarint(b, num, 1)
+		}
+	}
+	return b
+}
+
+// marshalProtoSources encodes Sources message of protoSchema.
+func marshalProtoSources(sources *geo.Sources) []byte {
+	var b []byte
+
+	b = appendProtoString(b, 1, sources.Country)
+	b = appendProtoString(b, 2, sources.City)
+	b = appendProtoString(b, 3, sources.Location)
+	return appendProtoString(b, 4, sources.ASN)
+}
+
+func appendProtoString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendProtoVarint(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendProtoDouble(b []byte, num protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
@@ -0,0 +1,232 @@
+package handle
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+func binaryTestInfo(t *testing.T) *conf.IPInfo {
+	cfg := newTestCfg(t)
+
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info.Sources = &geo.Sources{Country: "city", ASN: "asn"}
+	info.UTCOffsetSeconds = -3600
+	return info
+}
+
+// checkBinaryResponse calls handler and returns response body if status and headers are expected.
+func checkBinaryResponse(
+	t *testing.T,
+	handler func(http.ResponseWriter, *conf.IPInfo, *BuildInfo) error,
+	info *conf.IPInfo,
+	contentType string,
+) []byte {
+	w := httptest.NewRecorder()
+	if err := handler(w, info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("not %d status code: %v", http.StatusOK, resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != contentType {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+	checkNoCache(t, resp)
+
+	if err := resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+	return w.Body.Bytes()
+}
+
+// serialized returns a copy of info without fields which are not serialized.
+func serialized(info *conf.IPInfo) conf.IPInfo {
+	result := *info
+	result.Timestamp = time.Time{}
+	result.Addr = netip.Addr{}
+	return result
+}
+
+func TestMsgPackHandler(t *testing.T) {
+	info := binaryTestInfo(t)
+	body := checkBinaryResponse(t, MsgPackHandler, info, "application/msgpack")
+
+	decoder := msgpack.NewDecoder(bytes.NewReader(body))
+	decoder.SetCustomStructTag("json")
+
+	var result conf.IPInfo
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := serialized(info); !reflect.DeepEqual(result, expected) {
+		t.Errorf("not equal IPInfo %+v != %+v", result, expected)
+	}
+}
+
+func TestCBORHandler(t *testing.T) {
+	info := binaryTestInfo(t)
+	body := checkBinaryResponse(t, CBORHandler, info, "application/cbor")
+
+	var result conf.IPInfo
+	if err := cbor.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := serialized(info); !reflect.DeepEqual(result, expected) {
+		t.Errorf("not equal IPInfo %+v != %+v", result, expected)
+	}
+}
+
+func TestProtobufHandler(t *testing.T) {
+	info := binaryTestInfo(t)
+	body := checkBinaryResponse(t, ProtobufHandler, info, "application/x-protobuf")
+
+	var result conf.IPInfo
+	unmarshalProto(t, body, &result)
+
+	if expected := serialized(info); !reflect.DeepEqual(result, expected) {
+		t.Errorf("not equal IPInfo %+v != %+v", result, expected)
+	}
+
+	// zero values are omitted
+	if b := marshalProto(&conf.IPInfo{}); len(b) != 0 {
+		t.Errorf("not empty message: %v", b)
+	}
+}
+
+func TestProtoSchemaHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := ProtoSchemaHandler(w, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkNoCache(t, w.Result())
+
+	body := w.Body.String()
+	for _, s := range []string{"syntax = \"proto3\";", "message IPInfo {", "bool is_dst = 21;"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("schema doesn't contain %q", s)
+		}
+	}
+}
+
+// protoFieldTypes are scalar types of protobuf fields.
+var protoFieldTypes = map[string]descriptorpb.FieldDescriptorProto_Type{ //nolint:gochecknoglobals
+	"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint32": descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"uint64": descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"sint32": descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64": descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+	"float":  descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+}
+
+// parseProtoSchema builds a file descriptor of protoSchema, only its subset of proto3 syntax is supported:
+// package and messages with scalar or message fields.
+func parseProtoSchema(t *testing.T) protoreflect.FileDescriptor {
+	var (
+		packageRe = regexp.MustCompile(`^package ([\w.]+);`)
+		messageRe = regexp.MustCompile(`^message (\w+) \{`)
+		fieldRe   = regexp.MustCompile(`^(\w+) (\w+) = (\d+);`)
+		file      = &descriptorpb.FileDescriptorProto{Name: proto.String("ipinfo.proto"), Syntax: proto.String("proto3")}
+		message   *descriptorpb.DescriptorProto
+	)
+
+	for line := range strings.Lines(protoSchema) {
+		line = strings.TrimSpace(line)
+
+		if m := packageRe.FindStringSubmatch(line); m != nil {
+			file.Package = proto.String(m[1])
+		} else if m = messageRe.FindStringSubmatch(line); m != nil {
+			message = &descriptorpb.DescriptorProto{Name: proto.String(m[1])}
+			file.MessageType = append(file.MessageType, message)
+		} else if m = fieldRe.FindStringSubmatch(line); m != nil && message != nil {
+			num, err := strconv.ParseInt(m[3], 10, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(m[2]),
+				Number: proto.Int32(int32(num)),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}
+
+			if typ, ok := protoFieldTypes[m[1]]; ok {
+				field.Type = typ.Enum()
+			} else {
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String("." + file.GetPackage() + "." + m[1])
+			}
+			message.Field = append(message.Field, field)
+		}
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+// unmarshalProto decodes IPInfo message by the descriptor of protoSchema, unknown fields are errors.
+func unmarshalProto(t *testing.T, b []byte, info *conf.IPInfo) {
+	md := parseProtoSchema(t).Messages().ByName("IPInfo")
+	if md == nil {
+		t.Fatal("IPInfo message is not found")
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if unknown := msg.GetUnknown(); len(unknown) > 0 {
+		t.Errorf("unknown fields: %v", unknown)
+	}
+
+	// proto field names are JSON keys of IPInfo
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = json.Unmarshal(data, info); err != nil {
+		t.Fatal(err)
+	}
+}
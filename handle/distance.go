@@ -0,0 +1,125 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+// DistancePoint is a geo position of an IP address or coordinates.
+type DistancePoint struct {
+	IP             string  `json:"ip,omitempty"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AccuracyRadius uint16  `json:"accuracy_radius"`
+}
+
+// DistanceInfo is a great-circle distance and initial bearing between two points.
+type DistanceInfo struct {
+	From     DistancePoint `json:"from"`
+	To       DistancePoint `json:"to"`
+	Distance float64       `json:"distance_km"`
+	Bearing  float64       `json:"bearing"`
+}
+
+// DistanceHandler is handler for application/json response with distance between two points.
+// The start point is the client or "from" IP address, the end point is "to" IP address
+// or "lat" and "lon" coordinates. Accuracy radii of points are in kilometers, as the distance,
+// and the bearing is in degrees clockwise from north.
+func DistanceHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+	if _, err := acceptOffer(w, r, jsonOffers); err != nil {
+		return err
+	}
+
+	from := DistancePoint{
+		IP:             info.IP,
+		Latitude:       info.Latitude,
+		Longitude:      info.Longitude,
+		AccuracyRadius: info.AccuracyRadius,
+	}
+
+	var err error
+	if ip := r.FormValue("from"); ip != "" {
+		if from, err = lookupPoint(cfg, ip); err != nil {
+			return err
+		}
+	} else if !info.HasCoordinates() {
+		return &StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("location of %s is unknown", from.IP)}
+	}
+
+	to, err := targetPoint(r, cfg)
+	if err != nil {
+		return err
+	}
+
+	a := geo.Point{Latitude: from.Latitude, Longitude: from.Longitude}
+	b := geo.Point{Latitude: to.Latitude, Longitude: to.Longitude}
+	result := DistanceInfo{
+		From:     from,
+		To:       to,
+		Distance: round(geo.Distance(a, b), 3),
+		Bearing:  round(geo.Bearing(a, b), 2),
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return json.NewEncoder(w).Encode(result)
+}
+
+// targetPoint returns the end point by "to" IP address or "lat" and "lon" coordinates.
+func targetPoint(r *http.Request, cfg *conf.Cfg) (DistancePoint, error) {
+	if ip := r.FormValue("to"); ip != "" {
+		return lookupPoint(cfg, ip)
+	}
+
+	lat, lon := r.FormValue("lat"), r.FormValue("lon")
+	if lat == "" || lon == "" {
+		return DistancePoint{}, &StatusError{Code: http.StatusBadRequest, Err: errors.New("set target by to or lat and lon parameters")}
+	}
+
+	latitude, latErr := strconv.ParseFloat(lat, 64)
+	longitude, lonErr := strconv.ParseFloat(lon, 64)
+	point := geo.Point{Latitude: latitude, Longitude: longitude}
+
+	if latErr != nil || lonErr != nil || !point.Valid() {
+		return DistancePoint{}, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid coordinates %q, %q", lat, lon)}
+	}
+	return DistancePoint{Latitude: latitude, Longitude: longitude}, nil
+}
+
+// lookupPoint returns a point of the IP address, it should have known coordinates.
+func lookupPoint(cfg *conf.Cfg, ip string) (DistancePoint, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return DistancePoint{}, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid IP address %q", ip)}
+	}
+
+	record, err := cfg.Lookup(addr)
+	if err != nil {
+		return DistancePoint{}, err
+	}
+
+	if !record.Location.HasCoordinates() {
+		return DistancePoint{}, &StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("location of %s is unknown", ip)}
+	}
+
+	return DistancePoint{
+		IP:             ip,
+		Latitude:       record.Location.Latitude,
+		Longitude:      record.Location.Longitude,
+		AccuracyRadius: record.Location.AccuracyRadius,
+	}, nil
+}
+
+// round rounds the value to the number of decimal places.
+func round(value float64, places int) float64 {
+	scale := math.Pow10(places)
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,95 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDistanceHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	var (
+		malmo  = DistancePoint{IP: "193.138.218.226", Latitude: 55.6078, Longitude: 12.9982, AccuracyRadius: 20}
+		london = DistancePoint{IP: "81.2.69.1", Latitude: 51.5142, Longitude: -0.0931, AccuracyRadius: 10}
+	)
+	cases := []struct {
+		name     string
+		ip       string
+		query    string
+		expected DistanceInfo
+		code     int
+	}{
+		{
+			name:     "to ip",
+			ip:       malmo.IP,
+			query:    "?to=81.2.69.1",
+			expected: DistanceInfo{From: malmo, To: london, Distance: 974.992, Bearing: 247.62},
+		},
+		{
+			name:  "from ip",
+			ip:    "127.0.0.1",
+			query: "?from=81.2.69.1&to=193.138.218.226",
+			expected: DistanceInfo{
+				From: london, To: malmo, Distance: 974.992, Bearing: 57.07,
+			},
+		},
+		{
+			name:  "coordinates",
+			ip:    malmo.IP,
+			query: "?lat=51.5142&lon=-0.0931",
+			expected: DistanceInfo{
+				From: malmo, To: DistancePoint{Latitude: 51.5142, Longitude: -0.0931}, Distance: 974.992, Bearing: 247.62,
+			},
+		},
+		{name: "unknown client", ip: "127.0.0.1", query: "?to=81.2.69.1", code: http.StatusNotFound},
+		{name: "unknown target", ip: malmo.IP, query: "?to=127.0.0.1", code: http.StatusNotFound},
+		{name: "bad target", ip: malmo.IP, query: "?to=localhost", code: http.StatusBadRequest},
+		{name: "bad source", ip: malmo.IP, query: "?from=1.2.3&to=81.2.69.1", code: http.StatusBadRequest},
+		{name: "no target", ip: malmo.IP, code: http.StatusBadRequest},
+		{name: "only latitude", ip: malmo.IP, query: "?lat=51.5", code: http.StatusBadRequest},
+		{name: "bad latitude", ip: malmo.IP, query: "?lat=91&lon=0", code: http.StatusBadRequest},
+		{name: "bad longitude", ip: malmo.IP, query: "?lat=0&lon=east", code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/distance"+c.query, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		err = DistanceHandler(w, req, cfg, info)
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+		}
+		checkNoCache(t, resp)
+
+		result := DistanceInfo{}
+		if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result != c.expected {
+			t.Errorf("%s: not equal %v != %v", c.name, result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handle
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// exportPrefix is a prefix of variable names of shell and env formats.
+const exportPrefix = "IPINFO_"
+
+var (
+	// envReplacer escapes a value for double quotes of .env files.
+	envReplacer = strings.NewReplacer( //nolint:gochecknoglobals
+		`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`,
+	)
+	// markdownReplacer escapes a value for a table cell of Markdown.
+	markdownReplacer = strings.NewReplacer( //nolint:gochecknoglobals
+		`\`, `\\`, "|", `\|`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+		"<", `\<`, ">", `\>`, "~", `\~`, "\r\n", " ", "\n", " ", "\r", " ",
+	)
+)
+
+// ShellHandler is handler for text/plain response of POSIX shell export commands, it can be used by eval.
+func ShellHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	if err := exportHandler(w, "text/plain; charset=utf-8", info, encodeFieldsShell); err != nil {
+		return fmt.Errorf("ShellHandler: %w", err)
+	}
+	return nil
+}
+
+// EnvHandler is handler for text/plain response in .env file format.
+func EnvHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	if err := exportHandler(w, "text/plain; charset=utf-8", info, encodeFieldsEnv); err != nil {
+		return fmt.Errorf("EnvHandler: %w", err)
+	}
+	return nil
+}
+
+// MarkdownHandler is handler for text/markdown response with a table of attributes.
+func MarkdownHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	if err := exportHandler(w, "text/markdown; charset=utf-8", info, encodeFieldsMarkdown); err != nil {
+		return fmt.Errorf("MarkdownHandler: %w", err)
+	}
+	return nil
+}
+
+// exportHandler writes all attributes by the encoder.
+func exportHandler(w http.ResponseWriter, contentType string, info *conf.IPInfo, encode func(io.Writer, []fieldValue) error) error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	return encode(w, selectFields(infoFields, info))
+}
+
+// exportName returns a variable name of the field, e.g. IPINFO_COUNTRY_CODE.
+func exportName(name string) string {
+	return exportPrefix + strings.ToUpper(name)
+}
+
+// shellQuote returns the value in single quotes, they are safe for any characters except a single quote itself.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// encodeFieldsShell writes export commands with single-quoted values.
+func encodeFieldsShell(w io.Writer, values []fieldValue) error {
+	var err error
+
+	for _, v := range values {
+		err = printF(err, w, "export %s=%s\n", exportName(v.name), shellQuote(formatValue(v.value)))
+	}
+	return err
+}
+
+// encodeFieldsEnv writes variables with double-quoted and backslash-escaped values.
+func encodeFieldsEnv(w io.Writer, values []fieldValue) error {
+	var err error
+
+	for _, v := range values {
+		err = printF(err, w, "%s=\"%s\"\n", exportName(v.name), envReplacer.Replace(formatValue(v.value)))
+	}
+	return err
+}
+
+// encodeFieldsMarkdown writes a table of names and escaped values, names are safe and not escaped.
+func encodeFieldsMarkdown(w io.Writer, values []fieldValue) error {
+	err := printF(nil, w, "| Field | Value |\n|-------|-------|\n")
+
+	for _, v := range values {
+		err = printF(err, w, "| %s | %s |\n", v.name, markdownReplacer.Replace(formatValue(v.value)))
+	}
+	return err
+}
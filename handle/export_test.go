@@ -0,0 +1,111 @@
+package handle
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestExportHandlers(t *testing.T) {
+	cfg := newTestCfg(t)
+	cases := []struct {
+		name        string
+		handler     func(http.ResponseWriter, *conf.IPInfo, *BuildInfo) error
+		contentType string
+		lines       []string
+	}{
+		{
+			name:        "shell",
+			handler:     ShellHandler,
+			contentType: "text/plain; charset=utf-8",
+			lines:       []string{"export IPINFO_IP='193.138.218.226'", "export IPINFO_COUNTRY_CODE='SE'", "export IPINFO_ASN='39351'"},
+		},
+		{
+			name:        "env",
+			handler:     EnvHandler,
+			contentType: "text/plain; charset=utf-8",
+			lines:       []string{`IPINFO_IP="193.138.218.226"`, `IPINFO_SUBDIVISION="Skane County"`, `IPINFO_LATITUDE="55.6078"`},
+		},
+		{
+			name:        "markdown",
+			handler:     MarkdownHandler,
+			contentType: "text/markdown; charset=utf-8",
+			lines:       []string{"| Field | Value |", "| ip | 193.138.218.226 |", "| time_zone | Europe/Stockholm |"},
+		},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/"+c.name, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		if err = c.handler(w, info, nil); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s: not equal Content-Type %v != %v", c.name, ct, c.contentType)
+		}
+		checkNoCache(t, resp)
+
+		body := w.Body.String()
+		if n := strings.Count(body, "\n"); n < len(infoFields) {
+			t.Errorf("%s: not enough lines %d", c.name, n)
+		}
+
+		for _, line := range c.lines {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("%s: body doesn't contain %q: %s", c.name, line, body)
+			}
+		}
+	}
+}
+
+func TestExportEscaping(t *testing.T) {
+	values := []fieldValue{
+		{name: "city", value: "L'Aquila: $HOME `id` \"x\" a|b *c*\nd\\"},
+		{name: "asn", value: uint32(0)},
+	}
+	cases := []struct {
+		name     string
+		encode   func(io.Writer, []fieldValue) error
+		expected string
+	}{
+		{
+			name:     "shell",
+			encode:   encodeFieldsShell,
+			expected: "export IPINFO_CITY='L'\\''Aquila: $HOME `id` \"x\" a|b *c*\nd\\'\nexport IPINFO_ASN=''\n",
+		},
+		{
+			name:     "env",
+			encode:   encodeFieldsEnv,
+			expected: "IPINFO_CITY=\"L'Aquila: \\$HOME \\`id\\` \\\"x\\\" a|b *c*\\nd\\\\\"\nIPINFO_ASN=\"\"\n",
+		},
+		{
+			name:     "markdown",
+			encode:   encodeFieldsMarkdown,
+			expected: "| Field | Value |\n|-------|-------|\n| city | L'Aquila: $HOME \\`id\\` \"x\" a\\|b \\*c\\* d\\\\ |\n| asn |  |\n",
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+
+		if err := c.encode(&buf, values); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		if s := buf.String(); s != c.expected {
+			t.Errorf("%s: not equal\n%s\n!=\n%s", c.name, s, c.expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package handle
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// deniedResponses are default responses of denied requests by format.
+var deniedResponses = map[string]struct { //nolint:gochecknoglobals
+	contentType string
+	body        string
+}{
+	"text": {contentType: "text/plain; charset=utf-8", body: "Access denied\n"},
+	"json": {contentType: "application/json; charset=utf-8", body: `{"error":"access denied"}` + "\n"},
+	"xml":  {contentType: "application/xml; charset=utf-8", body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n<error>access denied</error>\n"},
+	"html": {contentType: "text/html; charset=utf-8", body: "<!DOCTYPE html>\n<html><body><h1>Access denied</h1></body></html>\n"},
+}
+
+// InfoHandlerFunc is a handler of requests with the client's info, it returns the response status code.
+type InfoHandlerFunc func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) int
+
+// GeoFence is a middleware which rejects requests denied by the access policy.
+// The response format is selected by URL path like handlers do, its body can be customized.
+// API v2 paths get the error envelope. AuthPath is skipped, its auth policy decides the response.
+func GeoFence(cfg *conf.Cfg, buildInfo *BuildInfo, next InfoHandlerFunc) InfoHandlerFunc {
+	if cfg.Access.IsEmpty() {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) int {
+		if cfg.Access.Allowed(info) || strings.TrimRight(r.URL.Path, "/ ") == AuthPath {
+			return next(w, r, info)
+		}
+
+		slog.Info("access denied", "ip", info.IP, "country", info.CountryCode, "asn", info.ASN, "path", r.URL.Path)
+		if IsV2Path(strings.TrimRight(r.URL.Path, "/ ")) {
+			return V2ErrorHandler(w, r, cfg, buildInfo, &StatusError{Code: cfg.Access.Status, Err: errors.New("access denied")})
+		}
+
+		format := responseFormat(r)
+		response := deniedResponses[format]
+
+		if body, ok := cfg.Access.Responses[format]; ok {
+			response.body = body
+		}
+
+		w.Header().Set("Content-Type", response.contentType)
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.WriteHeader(cfg.Access.Status)
+
+		if err := printF(nil, w, "%s", response.body); err != nil {
+			slog.Error("geo fence response", "error", err)
+		}
+		return cfg.Access.Status
+	}
+}
+
+// responseFormat returns format of the handler by URL path, the root one is negotiated by Accept header.
+func responseFormat(r *http.Request) string {
+	switch strings.TrimRight(r.URL.Path, "/ ") {
+	case "":
+		if offer, _, ok := negotiate(r.Header.Values("Accept"), mediaOffers); ok {
+			if format, found := offerFormats[offer.mediaType]; found {
+				return format
+			}
+		}
+	case "/json":
+		return "json"
+	case "/xml":
+		return "xml"
+	case "/html", "/full":
+		return "html"
+	}
+	return "text"
+}
+
+// offerFormats are response formats of denied requests by negotiated media type, others are "text".
+var offerFormats = map[string]string{ //nolint:gochecknoglobals
+	"text/html":        "html",
+	"application/json": "json",
+	"application/xml":  "xml",
+	"text/xml":         "xml",
+}
@@ -0,0 +1,191 @@
+package handle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestGeoFence(t *testing.T) {
+	const configName = "access.json"
+	config := `{
+		"ip_header": "X-Real-Ip",
+		"db": "` + mmdbtest.DBName + `",
+		"asn_db": "` + mmdbtest.ASNDBName + `",
+		"access": {
+			"deny": {"countries": ["RU"], "asns": [20712]},
+			"status": 451,
+			"responses": {"json": "{\"error\": \"unavailable\"}"}
+		}
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := conf.New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	next := func(w http.ResponseWriter, _ *http.Request, _ *conf.IPInfo) int {
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent
+	}
+	handler := GeoFence(cfg, &BuildInfo{Version: "v1"}, next)
+
+	cases := []struct {
+		ip          string
+		path        string
+		accept      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{ip: "193.138.218.226", path: "/json", code: http.StatusNoContent},
+		{ip: "127.0.0.1", path: "/", code: http.StatusNoContent},
+		{
+			ip:          "5.255.255.5",
+			path:        "/",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "text/plain; charset=utf-8",
+			body:        "Access denied\n",
+		},
+		{
+			ip:          "5.255.255.5",
+			path:        "/json/",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"error": "unavailable"}`,
+		},
+		{
+			ip:          "5.255.255.5",
+			path:        "/",
+			accept:      "text/html;q=0.5, application/json",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"error": "unavailable"}`,
+		},
+		{
+			ip:          "5.255.255.5",
+			path:        "/",
+			accept:      "application/cbor",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "text/plain; charset=utf-8",
+			body:        "Access denied\n",
+		},
+		{
+			ip:          "81.2.69.1",
+			path:        "/xml",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "application/xml; charset=utf-8",
+			body:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n<error>access denied</error>\n",
+		},
+		{
+			ip:          "81.2.69.1",
+			path:        "/full",
+			code:        http.StatusUnavailableForLegalReasons,
+			contentType: "text/html; charset=utf-8",
+			body:        "<!DOCTYPE html>\n<html><body><h1>Access denied</h1></body></html>\n",
+		},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.path, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		if c.accept != "" {
+			req.Header.Add("Accept", c.accept)
+		}
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w := httptest.NewRecorder()
+		code := handler(w, req, info)
+
+		resp := w.Result()
+		if code != c.code || resp.StatusCode != c.code {
+			t.Errorf("%s %s: not equal status code %d %d != %d", c.ip, c.path, code, resp.StatusCode, c.code)
+		}
+
+		if c.body == "" {
+			continue
+		}
+
+		checkNoCache(t, resp)
+		if ct := resp.Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s %s: not equal Content-Type: %v", c.ip, c.path, ct)
+		}
+
+		if body := w.Body.String(); body != c.body {
+			t.Errorf("%s %s: not equal body %q != %q", c.ip, c.path, body, c.body)
+		}
+	}
+
+	// API v2 paths get the error envelope
+	req := httptest.NewRequest("GET", "https://example.com/v2/schema.json", nil)
+	req.Header.Add("X-Real-Ip", "5.255.255.5")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if code := handler(w, req, info); code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("not equal v2 status code %d", code)
+	}
+
+	var response V2Response
+	if err = json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Error == nil || response.Error.Message != "access denied" || response.Meta.ServerVersion != "v1" {
+		t.Errorf("unexpected v2 response %+v", response)
+	}
+
+	// auth endpoint is checked by auth policy only
+	authNext := func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) int {
+		if authErr := AuthHandler(w, r, cfg, info); authErr != nil {
+			t.Error(authErr)
+		}
+		return w.(*httptest.ResponseRecorder).Code
+	}
+	for _, path := range []string{"/auth", "/auth/"} {
+		req = httptest.NewRequest("GET", "https://example.com"+path, nil)
+		req.Header.Add("X-Real-Ip", "5.255.255.5")
+
+		if info, err = cfg.Info(req); err != nil {
+			t.Fatal(err)
+		}
+
+		w = httptest.NewRecorder()
+		code := GeoFence(cfg, nil, authNext)(w, req, info)
+
+		if code != http.StatusOK && code != http.StatusForbidden {
+			t.Errorf("%s: unexpected auth status code %d", path, code)
+		}
+
+		if country := w.Result().Header.Get("X-Geo-Country"); country != "RU" {
+			t.Errorf("%s: not equal X-Geo-Country %q", path, country)
+		}
+	}
+
+	// no access policy
+	w = httptest.NewRecorder()
+	if code := GeoFence(newTestCfg(t), nil, next)(w, req, info); code != http.StatusNoContent {
+		t.Errorf("not equal status code without policy: %d", code)
+	}
+}
@@ -0,0 +1,405 @@
+package handle
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+	"gopkg.in/yaml.v3"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// infoField is a selectable attribute of IPInfo, its name is JSON key and protoNum is a field number of protoSchema.
+type infoField struct {
+	value    func(info *conf.IPInfo) any
+	name     string
+	protoNum protowire.Number
+}
+
+// infoFields are selectable attributes in the stable order of CSV columns, new ones should be appended to the end.
+var infoFields = []infoField{ //nolint:gochecknoglobals
+	{name: "ip", protoNum: 1, value: func(info *conf.IPInfo) any { return info.IP }},
+	{name: "country", protoNum: 2, value: func(info *conf.IPInfo) any { return info.Country }},
+	{name: "country_code", protoNum: 3, value: func(info *conf.IPInfo) any { return info.CountryCode }},
+	{name: "continent", protoNum: 4, value: func(info *conf.IPInfo) any { return info.Continent }},
+	{name: "subdivision", protoNum: 5, value: func(info *conf.IPInfo) any { return info.Subdivision }},
+	{name: "subdivision_code", protoNum: 6, value: func(info *conf.IPInfo) any { return info.SubdivisionCode }},
+	{name: "city", protoNum: 7, value: func(info *conf.IPInfo) any { return info.City }},
+	{name: "asn", protoNum: 9, value: func(info *conf.IPInfo) any { return info.ASN }},
+	{name: "as_organization", protoNum: 8, value: func(info *conf.IPInfo) any { return info.ASOrganization }},
+	{name: "latitude", protoNum: 14, value: func(info *conf.IPInfo) any { return info.Latitude }},
+	{name: "longitude", protoNum: 13, value: func(info *conf.IPInfo) any { return info.Longitude }},
+	{name: "accuracy_radius", protoNum: 16, value: func(info *conf.IPInfo) any { return info.AccuracyRadius }},
+	{name: "time_zone", protoNum: 11, value: func(info *conf.IPInfo) any { return info.TimeZone }},
+	{name: "tz_abbreviation", protoNum: 17, value: func(info *conf.IPInfo) any { return info.Abbreviation }},
+	{name: "utc_offset", protoNum: 18, value: func(info *conf.IPInfo) any { return info.UTCOffset }},
+	{name: "is_dst", protoNum: 21, value: func(info *conf.IPInfo) any { return info.IsDST }},
+	{name: "utc_time", protoNum: 10, value: func(info *conf.IPInfo) any { return info.UTCTime }},
+	{name: "language", protoNum: 12, value: func(info *conf.IPInfo) any { return info.Language }},
+	{name: "next_dst_transition", protoNum: 19, value: func(info *conf.IPInfo) any { return info.NextTransition }},
+	{name: "utc_offset_seconds", protoNum: 20, value: func(info *conf.IPInfo) any { return info.UTCOffsetSeconds }},
+}
+
+// fieldAliases are short names of fields, an alias can be expanded to several fields.
+var fieldAliases = map[string][]string{ //nolint:gochecknoglobals
+	"tz":     {"time_zone"},
+	"coords": {"latitude", "longitude"},
+}
+
+// fieldValue is a selected attribute value.
+type fieldValue struct {
+	value    any
+	name     string
+	protoNum protowire.Number
+}
+
+// fieldsEncoder writes selected attributes in some format.
+type fieldsEncoder struct {
+	encode      func(w io.Writer, values []fieldValue) error
+	contentType string
+}
+
+// fieldsEncoders are encoders of selected attributes by format name.
+var fieldsEncoders = map[string]fieldsEncoder{ //nolint:gochecknoglobals
+	"text":     {contentType: "text/plain; charset=utf-8", encode: encodeFieldsText},
+	"html":     {contentType: "text/html; charset=utf-8", encode: encodeFieldsHTML},
+	"json":     {contentType: "application/json; charset=utf-8", encode: encodeFieldsJSON},
+	"xml":      {contentType: "application/xml; charset=utf-8", encode: encodeFieldsXML},
+	"yaml":     {contentType: "application/yaml; charset=utf-8", encode: encodeFieldsYAML},
+	"csv":      {contentType: "text/csv; charset=utf-8", encode: encodeFieldsCSV},
+	"msgpack":  {contentType: "application/msgpack", encode: encodeFieldsMsgPack},
+	"cbor":     {contentType: "application/cbor", encode: encodeFieldsCBOR},
+	"protobuf": {contentType: "application/x-protobuf", encode: encodeFieldsProtobuf},
+	"shell":    {contentType: "text/plain; charset=utf-8", encode: encodeFieldsShell},
+	"env":      {contentType: "text/plain; charset=utf-8", encode: encodeFieldsEnv},
+	"markdown": {contentType: "text/markdown; charset=utf-8", encode: encodeFieldsMarkdown},
+}
+
+// fieldsPaths are format names of handler paths which support fields selection, others are negotiated.
+var fieldsPaths = map[string]string{ //nolint:gochecknoglobals
+	"/short":    "text",
+	"/compact":  "text",
+	"/json":     "json",
+	"/xml":      "xml",
+	"/yaml":     "yaml",
+	"/csv":      "csv",
+	"/msgpack":  "msgpack",
+	"/cbor":     "cbor",
+	"/protobuf": "protobuf",
+	"/html":     "html",
+	"/full":     "html",
+	"/shell":    "shell",
+	"/env":      "env",
+	"/markdown": "markdown",
+}
+
+var htmlFieldsTemplate = template.Must(template.New("fields").Parse( //nolint:gochecknoglobals
+	"<!DOCTYPE html>\n<html>\n<head>\n  <meta charset=\"UTF-8\">\n  <title>IPInfo</title>\n</head>\n<body>\n<table>\n" +
+		"{{ range . }}  <tr>\n    <td>{{ .Name }}</td>\n    <td>{{ .Value }}</td>\n  </tr>\n{{ end }}" +
+		"</table>\n</body>\n</html>\n",
+))
+
+// IsFieldsRequest returns true if the request selects fields of a handler which supports it.
+func IsFieldsRequest(r *http.Request, path string) bool {
+	_, ok := fieldsPaths[path]
+	return ok && r.FormValue("fields") != ""
+}
+
+// FieldsHandler is handler for responses with only selected by "fields" parameter attributes.
+// The format is selected by URL path, "output" parameter or negotiated by Accept header.
+func FieldsHandler(w http.ResponseWriter, r *http.Request, _ *conf.Cfg, info *conf.IPInfo) error {
+	fields, err := parseFields(r.FormValue("fields"))
+	if err != nil {
+		return &StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	format, ok := fieldsPaths[strings.TrimRight(r.URL.Path, "/ ")]
+	if !ok {
+		if format, err = fieldsFormat(w, r); err != nil {
+			return err
+		}
+	}
+
+	encoder := fieldsEncoders[format]
+	w.Header().Set("Content-Type", encoder.contentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if err = encoder.encode(w, selectFields(fields, info)); err != nil {
+		return fmt.Errorf("FieldsHandler: %w", err)
+	}
+	return nil
+}
+
+// fieldsFormat returns format of the default route by "output" parameter or Accept header.
+func fieldsFormat(w http.ResponseWriter, r *http.Request) (string, error) {
+	if output := r.FormValue("output"); output != "" {
+		if output == "text" {
+			return output, nil
+		}
+
+		if format, ok := fieldsPaths["/"+output]; ok {
+			return format, nil
+		}
+		return "", &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("unknown output %q", output)}
+	}
+
+	offer, err := acceptOffer(w, r, mediaOffers)
+	if err != nil {
+		return "", err
+	}
+	return offer.format, nil
+}
+
+// FieldHandler returns handler for text/plain response with bare values of a field or an alias,
+// several values are separated by comma, e.g. "55.6078,12.9982" for "coords".
+func FieldHandler(name string) func(http.ResponseWriter, *conf.IPInfo, *BuildInfo) error {
+	fields, err := parseFields(name)
+	if err != nil {
+		panic(err) // only known names are used by routes
+	}
+
+	return func(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i] = formatValue(field.value(info))
+		}
+		return printF(nil, w, "%s\n", strings.Join(values, ","))
+	}
+}
+
+// fieldsParam returns fields of "fields" parameter or nil if it's not set.
+func fieldsParam(r *http.Request) ([]infoField, error) {
+	value := r.FormValue("fields")
+	if value == "" {
+		return nil, nil
+	}
+
+	fields, err := parseFields(value)
+	if err != nil {
+		return nil, &StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+	return fields, nil
+}
+
+// parseFields returns fields by comma separated names or aliases, duplicates are skipped.
+func parseFields(value string) ([]infoField, error) {
+	var fields []infoField
+
+	for item := range strings.SplitSeq(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(item))
+		if name == "" {
+			continue
+		}
+
+		names, ok := fieldAliases[name]
+		if !ok {
+			names = []string{name}
+		}
+
+		for _, n := range names {
+			i := slices.IndexFunc(infoFields, func(f infoField) bool { return f.name == n })
+			if i < 0 {
+				return nil, fmt.Errorf("unknown field %q", n)
+			}
+
+			if !slices.ContainsFunc(fields, func(f infoField) bool { return f.name == n }) {
+				fields = append(fields, infoFields[i])
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("no fields")
+	}
+	return fields, nil
+}
+
+// selectFields returns values of the fields in their order.
+func selectFields(fields []infoField, info *conf.IPInfo) []fieldValue {
+	values := make([]fieldValue, len(fields))
+
+	for i, field := range fields {
+		values[i] = fieldValue{name: field.name, protoNum: field.protoNum, value: field.value(info)}
+	}
+	return values
+}
+
+// formatValue returns a string representation of a field value, unknown autonomous system number is empty.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case uint32:
+		return formatASN(v)
+	case float64:
+		return formatFloat(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func encodeFieldsText(w io.Writer, values []fieldValue) error {
+	var err error
+
+	for _, v := range values {
+		err = printF(err, w, "%s: %s\n", v.name, formatValue(v.value))
+	}
+	return err
+}
+
+func encodeFieldsHTML(w io.Writer, values []fieldValue) error {
+	rows := make([]struct{ Name, Value string }, len(values))
+
+	for i, v := range values {
+		rows[i].Name, rows[i].Value = v.name, formatValue(v.value)
+	}
+	return htmlFieldsTemplate.Execute(w, rows)
+}
+
+// encodeFieldsJSON writes an object with keys in the selection order.
+func encodeFieldsJSON(w io.Writer, values []fieldValue) error {
+	b, err := marshalFieldsJSON(values)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// marshalFieldsJSON returns an object with keys in the selection order.
+func marshalFieldsJSON(values []fieldValue) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		value, err := json.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(strconv.Quote(v.name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func encodeFieldsXML(w io.Writer, values []fieldValue) error {
+	err := printF(nil, w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Local: "ipinfo"}}
+
+	if err = encoder.EncodeToken(root); err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if err = encoder.EncodeElement(v.value, xml.StartElement{Name: xml.Name{Local: v.name}}); err != nil {
+			return err
+		}
+	}
+
+	if err = encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// encodeFieldsYAML writes a mapping with keys in the selection order.
+func encodeFieldsYAML(w io.Writer, values []fieldValue) error {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, v := range values {
+		value := &yaml.Node{}
+		if err := value.Encode(v.value); err != nil {
+			return err
+		}
+
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v.name}, value)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(mapping); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func encodeFieldsCSV(w io.Writer, values []fieldValue) error {
+	writer := csv.NewWriter(w)
+	header, row := make([]string, len(values)), make([]string, len(values))
+
+	for i, v := range values {
+		header[i], row[i] = v.name, formatValue(v.value)
+	}
+
+	return writer.WriteAll([][]string{header, row})
+}
+
+// encodeFieldsMsgPack writes a map with keys in the selection order.
+func encodeFieldsMsgPack(w io.Writer, values []fieldValue) error {
+	encoder := msgpack.NewEncoder(w)
+
+	if err := encoder.EncodeMapLen(len(values)); err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if err := encoder.EncodeString(v.name); err != nil {
+			return err
+		}
+
+		if err := encoder.Encode(v.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeFieldsCBOR(w io.Writer, values []fieldValue) error {
+	m := make(map[string]any, len(values))
+
+	for _, v := range values {
+		m[v.name] = v.value
+	}
+	return cbor.NewEncoder(w).Encode(m)
+}
+
+// encodeFieldsProtobuf writes IPInfo message of protoSchema with only selected fields.
+func encodeFieldsProtobuf(w io.Writer, values []fieldValue) error {
+	var b []byte
+
+	for _, v := range values {
+		b = appendProtoValue(b, v.protoNum, v.value)
+	}
+
+	_, err := w.Write(b)
+	return err
+}
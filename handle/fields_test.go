@@ -0,0 +1,264 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestParseFields(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected []string
+		err      bool
+	}{
+		{value: "ip", expected: []string{"ip"}},
+		{value: "ip, Country ,city", expected: []string{"ip", "country", "city"}},
+		{value: "coords,latitude,tz", expected: []string{"latitude", "longitude", "time_zone"}},
+		{value: "asn,,ip,", expected: []string{"asn", "ip"}},
+		{value: "ip,unknown", err: true},
+		{value: "sources", err: true},
+		{value: " , ", err: true},
+	}
+	for _, c := range cases {
+		fields, err := parseFields(c.value)
+		if c.err {
+			if err == nil {
+				t.Errorf("%q: expected error", c.value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.value, err)
+			continue
+		}
+
+		names := make([]string, len(fields))
+		for i := range fields {
+			names[i] = fields[i].name
+		}
+
+		if !slices.Equal(names, c.expected) {
+			t.Errorf("%q: not equal fields %v != %v", c.value, names, c.expected)
+		}
+	}
+}
+
+func TestFieldHandler(t *testing.T) {
+	cases := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{name: "ip", ip: "193.138.218.226", expected: "193.138.218.226\n"},
+		{name: "country", ip: "193.138.218.226", expected: "Sweden\n"},
+		{name: "city", ip: "5.255.255.5", expected: "Москва\n"},
+		{name: "city", ip: "127.0.0.1", expected: "\n"},
+		{name: "tz", ip: "81.2.69.142", expected: "Europe/London\n"},
+		{name: "coords", ip: "193.138.218.226", expected: "55.6078,12.9982\n"},
+	}
+	cfg := newTestCfg(t)
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/"+c.name, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		if err = FieldHandler(c.name)(w, info, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+		}
+		checkNoCache(t, resp)
+
+		if body := w.Body.String(); body != c.expected {
+			t.Errorf("%s: not equal body %q != %q", c.name, body, c.expected)
+		}
+	}
+}
+
+func TestFieldsHandler(t *testing.T) {
+	const fields = "ip,asn,coords,is_dst"
+	cases := []struct {
+		path        string
+		accept      string
+		contentType string
+		expected    string
+	}{
+		{
+			path:        "/json",
+			contentType: "application/json; charset=utf-8",
+			expected:    `{"ip":"193.138.218.226","asn":39351,"latitude":55.6078,"longitude":12.9982,"is_dst":%v}` + "\n",
+		},
+		{
+			path:        "/xml",
+			contentType: "application/xml; charset=utf-8",
+			expected: `<?xml version="1.0" encoding="UTF-8"?>` + "\n<ipinfo><ip>193.138.218.226</ip><asn>39351</asn>" +
+				"<latitude>55.6078</latitude><longitude>12.9982</longitude><is_dst>%v</is_dst></ipinfo>",
+		},
+		{
+			path:        "/yaml",
+			contentType: "application/yaml; charset=utf-8",
+			expected:    "ip: 193.138.218.226\nasn: 39351\nlatitude: 55.6078\nlongitude: 12.9982\nis_dst: %v\n",
+		},
+		{
+			path:        "/csv",
+			contentType: "text/csv; charset=utf-8",
+			expected:    "ip,asn,latitude,longitude,is_dst\n193.138.218.226,39351,55.6078,12.9982,%v\n",
+		},
+		{
+			path:        "/short",
+			contentType: "text/plain; charset=utf-8",
+			expected:    "ip: 193.138.218.226\nasn: 39351\nlatitude: 55.6078\nlongitude: 12.9982\nis_dst: %v\n",
+		},
+		{
+			path:        "/",
+			accept:      "application/json",
+			contentType: "application/json; charset=utf-8",
+			expected:    `{"ip":"193.138.218.226","asn":39351,"latitude":55.6078,"longitude":12.9982,"is_dst":%v}` + "\n",
+		},
+		{
+			path:        "/unknown",
+			contentType: "text/plain; charset=utf-8",
+			expected:    "ip: 193.138.218.226\nasn: 39351\nlatitude: 55.6078\nlongitude: 12.9982\nis_dst: %v\n",
+		},
+	}
+	cfg := newTestCfg(t)
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.path+"?fields="+fields, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		if c.accept != "" {
+			req.Header.Add("Accept", c.accept)
+		}
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		if c.path == "/" || c.path == "/unknown" {
+			err = NegotiateHandler(w, req, cfg, info)
+		} else {
+			err = FieldsHandler(w, req, cfg, info)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s: not equal Content-Type: %v", c.path, ct)
+		}
+		checkNoCache(t, resp)
+
+		expected := strings.ReplaceAll(c.expected, "%v", formatValue(info.IsDST))
+		if body := w.Body.String(); body != expected {
+			t.Errorf("%s: not equal body %q != %q", c.path, body, expected)
+		}
+	}
+}
+
+func TestFieldsHandlerBinary(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	req := httptest.NewRequest("GET", "https://example.com/?fields=ip,asn,accuracy_radius", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/msgpack", "/cbor", "/protobuf"} {
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+
+		if err = FieldsHandler(w, req, cfg, info); err != nil {
+			t.Fatal(err)
+		}
+
+		var result map[string]any
+		switch path {
+		case "/msgpack":
+			err = msgpack.Unmarshal(w.Body.Bytes(), &result)
+		case "/cbor":
+			err = cbor.Unmarshal(w.Body.Bytes(), &result)
+		case "/protobuf":
+			protoInfo := &conf.IPInfo{}
+			unmarshalProto(t, w.Body.Bytes(), protoInfo)
+			result = map[string]any{"ip": protoInfo.IP, "asn": protoInfo.ASN, "accuracy_radius": protoInfo.AccuracyRadius}
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		if len(result) != 3 || result["ip"] != "193.138.218.226" {
+			t.Errorf("%s: unexpected result %v", path, result)
+		}
+
+		if s := formatValue(result["asn"]); s != "39351" {
+			t.Errorf("%s: not equal asn %v", path, s)
+		}
+
+		if s := formatValue(result["accuracy_radius"]); s != "20" {
+			t.Errorf("%s: not equal accuracy_radius %v", path, s)
+		}
+	}
+}
+
+func TestFieldsHandlerError(t *testing.T) {
+	cfg := newTestCfg(t)
+	cases := []struct {
+		url    string
+		accept string
+		code   int
+	}{
+		{url: "/json?fields=ip,password", code: http.StatusBadRequest},
+		{url: "/?fields=ip", accept: "image/png", code: http.StatusNotAcceptable},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.url, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+		req.Header.Add("Accept", c.accept)
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var statusErr *StatusError
+		err = FieldsHandler(httptest.NewRecorder(), req, cfg, info)
+
+		if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+			t.Errorf("%s: expected status error %d: %v", c.url, c.code, err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "https://example.com/json?fields=ip", nil)
+	if !IsFieldsRequest(req, "/json") || IsFieldsRequest(req, "/time") {
+		t.Error("unexpected fields request state")
+	}
+}
@@ -0,0 +1,83 @@
+package handle
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// YAMLHandler is handler for application/yaml response.
+func YAMLHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(info); err != nil {
+		return fmt.Errorf("YAMLHandler: %w", err)
+	}
+	return encoder.Close()
+}
+
+// CSVHandler is handler for text/csv response with a header row.
+func CSVHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if err := writeCSV(w, infoFields, info); err != nil {
+		return fmt.Errorf("CSVHandler: %w", err)
+	}
+	return nil
+}
+
+// writeCSV writes the header row and a row per info, so it's suitable for several addresses.
+// Columns are the fields, nil ones are all infoFields.
+func writeCSV(w io.Writer, fields []infoField, infos ...*conf.IPInfo) error {
+	if fields == nil {
+		fields = infoFields
+	}
+
+	writer := csv.NewWriter(w)
+	row := make([]string, len(fields))
+
+	for i, field := range fields {
+		row[i] = field.name
+	}
+
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		for i, field := range fields {
+			row[i] = formatValue(field.value(info))
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatASN returns autonomous system number or empty string if it's unknown.
+func formatASN(asn uint32) string {
+	if asn == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(asn), 10)
+}
+
+// formatFloat returns the shortest representation of the value.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
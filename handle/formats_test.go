@@ -0,0 +1,169 @@
+package handle
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestYAMLHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	req := httptest.NewRequest("GET", "https://example.com/yaml", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err = YAMLHandler(w, info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("not %d status code: %v", http.StatusOK, resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/yaml; charset=utf-8" {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+	checkNoCache(t, resp)
+
+	responseInfo := &conf.IPInfo{}
+	if err = yaml.NewDecoder(resp.Body).Decode(responseInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &conf.IPInfo{
+		IP:              "193.138.218.226",
+		Country:         "Sweden",
+		CountryCode:     "SE",
+		Continent:       "EU",
+		Subdivision:     "Skane County",
+		SubdivisionCode: "SE-M",
+		ASOrganization:  "31173 Services AB",
+		ASN:             39351,
+		City:            "Malmo",
+		Longitude:       12.9982,
+		Latitude:        55.6078,
+		TimeZone:        "Europe/Stockholm",
+		Language:        "en",
+		AccuracyRadius:  20,
+		UTCTime:         info.UTCTime,
+		TimeZoneInfo:    info.TimeZoneInfo,
+	}
+	if *responseInfo != *expected {
+		t.Errorf("not equal IPInfo: %v", responseInfo)
+	}
+
+	if err = resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCSVHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	req := httptest.NewRequest("GET", "https://example.com/csv", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err = CSVHandler(w, info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("not %d status code: %v", http.StatusOK, resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+	checkNoCache(t, resp)
+
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{
+			"ip", "country", "country_code", "continent", "subdivision", "subdivision_code", "city",
+			"asn", "as_organization", "latitude", "longitude", "accuracy_radius", "time_zone",
+			"tz_abbreviation", "utc_offset", "is_dst", "utc_time", "language", "next_dst_transition", "utc_offset_seconds",
+		},
+		{
+			"193.138.218.226", "Sweden", "SE", "EU", "Skane County", "SE-M", "Malmo",
+			"39351", "31173 Services AB", "55.6078", "12.9982", "20", "Europe/Stockholm",
+			info.Abbreviation, info.UTCOffset, strconv.FormatBool(info.IsDST), info.UTCTime, "en",
+			info.NextTransition, strconv.Itoa(info.UTCOffsetSeconds),
+		},
+	}
+	if !slices.EqualFunc(rows, expected, slices.Equal) {
+		t.Errorf("not equal rows %v != %v", rows, expected)
+	}
+
+	if err = resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	infos := []*conf.IPInfo{
+		{IP: "5.255.255.5", Country: "Россия", City: "Москва, центр", Latitude: 55.7527},
+		{IP: "127.0.0.1"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, nil, infos...); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(rows); n != 3 {
+		t.Fatalf("not equal rows number %d != 3", n)
+	}
+
+	for i, row := range rows[1:] {
+		if len(row) != len(infoFields) {
+			t.Errorf("row %d: not equal columns number %d != %d", i, len(row), len(infoFields))
+		}
+
+		if row[0] != infos[i].IP {
+			t.Errorf("row %d: not equal ip %v != %v", i, row[0], infos[i].IP)
+		}
+
+		if row[7] != "" {
+			t.Errorf("row %d: unknown asn should be empty: %q", i, row[7])
+		}
+	}
+
+	if city := rows[1][6]; city != "Москва, центр" {
+		t.Errorf("not equal city %q", city)
+	}
+
+	if lat := rows[2][9]; lat != "0" {
+		t.Errorf("not equal latitude %q", lat)
+	}
+}
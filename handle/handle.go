@@ -49,6 +49,22 @@ type XMLInfo struct {
 	conf.IPInfo          //nolint:embeddedstructfieldcheck
 }
 
+// StatusError is an error with HTTP status code which should be returned to the client.
+type StatusError struct {
+	Err  error
+	Code int
+}
+
+// Error returns error message.
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
 // TextHandler is handler for text/plain response.
 func TextHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -73,6 +89,7 @@ func TextShortHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo) er
 	err = printF(err, w, "Country:    %v\n", info.Country)
 	err = printF(err, w, "City:       %v\n", info.City)
 	err = printF(err, w, "Local time: %v\n", info.LocalTime())
+	err = printF(err, w, "UTC offset: %v %v\n", info.UTCOffset, info.Abbreviation)
 	return printF(err, w, "UTC time:   %v\n", info.UTCTime)
 }
 
@@ -85,7 +102,7 @@ func TextCompactHandler(w http.ResponseWriter, info *conf.IPInfo, _ *BuildInfo)
 	err = printF(err, w, "%s\n", info.IP)
 
 	_, localTime := info.LocalDateTime()
-	return printF(err, w, "%s\n", localTime)
+	return printF(err, w, "%s %s\n", localTime, info.UTCOffset)
 }
 
 // JSONHandler is handler for application/json response.
@@ -6,30 +6,45 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+	"github.com/z0rr0/ipinfo/internal/testenv"
 )
 
-const testConfigName = "/tmp/ipinfo_test.json"
+const testConfigName = mmdbtest.ConfigName
 
-func checkNoCache(t *testing.T, resp *http.Response) {
-	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache, no-store, must-revalidate" {
-		t.Errorf("Cache-Control: %v", resp.Header.Get("Cache-Control"))
-	}
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
 }
 
-func TestJSONHandler(t *testing.T) {
+// newTestCfg returns configuration for generated fixture database.
+func newTestCfg(t *testing.T) *conf.Cfg {
 	cfg, err := conf.New(testConfigName)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
+
+	t.Cleanup(func() {
 		if closeErr := cfg.Close(); closeErr != nil {
 			t.Errorf("close error: %v", closeErr)
 		}
-	}()
+	})
+	return cfg
+}
+
+func checkNoCache(t *testing.T, resp *http.Response) {
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache, no-store, must-revalidate" {
+		t.Errorf("Cache-Control: %v", resp.Header.Get("Cache-Control"))
+	}
+}
+
+func TestJSONHandler(t *testing.T) {
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -62,16 +77,24 @@ func TestJSONHandler(t *testing.T) {
 	}
 
 	expected := &conf.IPInfo{
-		IP:        "193.138.218.226",
-		Country:   "Sweden",
-		City:      "Malmo",
-		Longitude: 12.9982,
-		Latitude:  55.6078,
-		TimeZone:  "Europe/Stockholm",
-		Language:  "en",
+		IP:              "193.138.218.226",
+		Country:         "Sweden",
+		CountryCode:     "SE",
+		Continent:       "EU",
+		Subdivision:     "Skane County",
+		SubdivisionCode: "SE-M",
+		ASOrganization:  "31173 Services AB",
+		ASN:             39351,
+		City:            "Malmo",
+		Longitude:       12.9982,
+		Latitude:        55.6078,
+		TimeZone:        "Europe/Stockholm",
+		Language:        "en",
+		AccuracyRadius:  20,
 		// don't check time fields
-		UTCTime:   info.UTCTime,
-		Timestamp: responseInfo.Timestamp,
+		UTCTime:      info.UTCTime,
+		Timestamp:    responseInfo.Timestamp,
+		TimeZoneInfo: info.TimeZoneInfo,
 	}
 	if *responseInfo != *expected {
 		t.Errorf("not equal JSONInfo: %v", info)
@@ -83,15 +106,7 @@ func TestJSONHandler(t *testing.T) {
 }
 
 func TestXMLHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -126,16 +141,24 @@ func TestXMLHandler(t *testing.T) {
 	expected := &XMLInfo{
 		XMLName: responseInfo.XMLName, // don't check name
 		IPInfo: conf.IPInfo{
-			IP:        "193.138.218.226",
-			Country:   "Sweden",
-			City:      "Malmo",
-			Longitude: 12.9982,
-			Latitude:  55.6078,
-			TimeZone:  "Europe/Stockholm",
-			Language:  "en",
+			IP:              "193.138.218.226",
+			Country:         "Sweden",
+			CountryCode:     "SE",
+			Continent:       "EU",
+			Subdivision:     "Skane County",
+			SubdivisionCode: "SE-M",
+			ASOrganization:  "31173 Services AB",
+			ASN:             39351,
+			City:            "Malmo",
+			Longitude:       12.9982,
+			Latitude:        55.6078,
+			TimeZone:        "Europe/Stockholm",
+			Language:        "en",
+			AccuracyRadius:  20,
 			// don't check time
-			UTCTime:   responseInfo.UTCTime,
-			Timestamp: responseInfo.Timestamp,
+			UTCTime:      responseInfo.UTCTime,
+			Timestamp:    responseInfo.Timestamp,
+			TimeZoneInfo: info.TimeZoneInfo,
 		},
 	}
 	if *responseInfo != *expected {
@@ -148,15 +171,7 @@ func TestXMLHandler(t *testing.T) {
 }
 
 func TestTextShortHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -201,15 +216,7 @@ func TestTextShortHandler(t *testing.T) {
 }
 
 func TestTextHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo?b=1&c=3", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -251,18 +258,29 @@ func TestTextHandler(t *testing.T) {
 	if !strings.Contains(strBody, subStr) {
 		t.Fatalf("not found required second sub-string: %v", strBody)
 	}
-}
 
-func TestHTMLHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
+	subStr = "Time zone: Europe/Stockholm\nUTC offset: " + info.UTCOffset + "\nTime zone abbreviation: " + info.Abbreviation
+	if !strings.Contains(strBody, subStr) {
+		t.Errorf("not found time zone details: %v", strBody)
+	}
+
+	if strings.Contains(strBody, "Sources:") {
+		t.Errorf("unexpected sources: %v", strBody)
+	}
+
+	info.Sources = &geo.Sources{Country: "a.mmdb", City: "b.mmdb"}
+	w = httptest.NewRecorder()
+	if err = TextHandler(w, req, cfg, info); err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+
+	if body := w.Body.String(); !strings.HasSuffix(body, "Sources: country=a.mmdb, city=b.mmdb, location=\n") {
+		t.Errorf("not found sources: %v", body)
+	}
+}
+
+func TestHTMLHandler(t *testing.T) {
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -304,6 +322,9 @@ func TestHTMLHandler(t *testing.T) {
 		"<td>12.9982</td>",
 		"<td>Time zone</td>",
 		"<td>Europe/Stockholm</td>",
+		"<td>UTC offset</td>",
+		" " + info.Abbreviation + "</td>",
+		"<td>Next DST transition</td>",
 		"<td>Language</td>",
 		"<td>en</td>",
 	}
@@ -315,15 +336,7 @@ func TestHTMLHandler(t *testing.T) {
 }
 
 func TestVersionHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -369,15 +382,7 @@ func TestVersionHandler(t *testing.T) {
 }
 
 func TestTestCompactHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -417,15 +422,7 @@ func TestTestCompactHandler(t *testing.T) {
 }
 
 func TestFullHTMLHandler(t *testing.T) {
-	cfg, err := conf.New(testConfigName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if closeErr := cfg.Close(); closeErr != nil {
-			t.Errorf("close error: %v", closeErr)
-		}
-	}()
+	cfg := newTestCfg(t)
 
 	req := httptest.NewRequest("GET", "https://example.com/foo", nil)
 	req.Header.Add("X-Real-Ip", "193.138.218.226")
@@ -469,6 +466,9 @@ func TestFullHTMLHandler(t *testing.T) {
 		"<td>12.9982</td>",
 		"<td>Time zone</td>",
 		"<td>Europe/Stockholm</td>",
+		"<td>UTC offset</td>",
+		" " + info.Abbreviation + "</td>",
+		"<td>Next DST transition</td>",
 		"<td>Language</td>",
 		"<td>en</td>",
 	}
@@ -0,0 +1,272 @@
+package handle
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+// circleSegments is a number of segments of accuracy radius polygons.
+const circleSegments = 64
+
+// GeoJSONFeature is a GeoJSON (RFC 7946) feature, its geometry is null if coordinates are unknown.
+// If fields are selected, properties have only them.
+type GeoJSONFeature struct {
+	Geometry   *GeoJSONGeometry `json:"geometry"`
+	Properties *conf.IPInfo     `json:"properties"`
+	Type       string           `json:"type"`
+	fields     []infoField
+}
+
+// GeoJSONGeometry is a GeoJSON geometry: Point, Polygon or GeometryCollection of them.
+type GeoJSONGeometry struct {
+	Coordinates any               `json:"coordinates,omitempty"`
+	Type        string            `json:"type"`
+	Geometries  []GeoJSONGeometry `json:"geometries,omitempty"`
+}
+
+// GeoJSONFeatureCollection is a GeoJSON feature collection.
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// geoJSONOffers and kmlOffers are formats of map handlers.
+var (
+	geoJSONOffers = []mediaOffer{{mediaType: "application/geo+json", format: "geojson"}}             //nolint:gochecknoglobals
+	kmlOffers     = []mediaOffer{{mediaType: "application/vnd.google-earth.kml+xml", format: "kml"}} //nolint:gochecknoglobals
+)
+
+// GeoJSONHandler is handler for application/geo+json response with a Feature of Point geometry.
+// If "circle" parameter is true, the geometry is a collection of the point and a polygon of accuracy radius.
+func GeoJSONHandler(w http.ResponseWriter, r *http.Request, _ *conf.Cfg, info *conf.IPInfo) error {
+	if _, err := acceptOffer(w, r, geoJSONOffers); err != nil {
+		return err
+	}
+
+	circle, fields, err := mapParams(r)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return json.NewEncoder(w).Encode(newGeoJSONFeature(info, circle, fields))
+}
+
+// KMLHandler is handler for KML response with a Placemark of the client's location.
+// If "circle" parameter is true, the placemark also has a polygon of accuracy radius.
+func KMLHandler(w http.ResponseWriter, r *http.Request, _ *conf.Cfg, info *conf.IPInfo) error {
+	if _, err := acceptOffer(w, r, kmlOffers); err != nil {
+		return err
+	}
+
+	circle, fields, err := mapParams(r)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if err = writeKML(w, circle, fields, info); err != nil {
+		return fmt.Errorf("KMLHandler: %w", err)
+	}
+	return nil
+}
+
+// circleParam returns value of "circle" parameter, it's false by default.
+func circleParam(r *http.Request) (bool, error) {
+	value := r.FormValue("circle")
+	if value == "" {
+		return false, nil
+	}
+
+	circle, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid circle %q", value)}
+	}
+	return circle, nil
+}
+
+// mapParams returns "circle" and "fields" parameters of map formats.
+func mapParams(r *http.Request) (bool, []infoField, error) {
+	circle, err := circleParam(r)
+	if err != nil {
+		return false, nil, err
+	}
+
+	fields, err := fieldsParam(r)
+	if err != nil {
+		return false, nil, err
+	}
+	return circle, fields, nil
+}
+
+// MarshalJSON implements json.Marshaler, properties have only selected fields if they're set.
+func (f GeoJSONFeature) MarshalJSON() ([]byte, error) {
+	type feature GeoJSONFeature
+	if f.fields == nil || f.Properties == nil {
+		return json.Marshal(feature(f))
+	}
+
+	properties, err := marshalFieldsJSON(selectFields(f.fields, f.Properties))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Geometry   *GeoJSONGeometry `json:"geometry"`
+		Type       string           `json:"type"`
+		Properties json.RawMessage  `json:"properties"`
+	}{Geometry: f.Geometry, Properties: properties, Type: f.Type})
+}
+
+// newGeoJSONFeature returns a feature of info, nil fields are all ones.
+func newGeoJSONFeature(info *conf.IPInfo, circle bool, fields []infoField) *GeoJSONFeature {
+	feature := &GeoJSONFeature{Type: "Feature", Properties: info, fields: fields}
+	if !info.HasCoordinates() {
+		return feature
+	}
+
+	point := GeoJSONGeometry{Type: "Point", Coordinates: geoJSONPosition(infoPoint(info))}
+	if !circle || info.AccuracyRadius == 0 {
+		feature.Geometry = &point
+		return feature
+	}
+
+	ring := geo.Circle(infoPoint(info), float64(info.AccuracyRadius), circleSegments)
+	positions := make([][]float64, len(ring))
+
+	for i, p := range ring {
+		positions[i] = geoJSONPosition(p)
+	}
+
+	polygon := GeoJSONGeometry{Type: "Polygon", Coordinates: [][][]float64{positions}}
+	feature.Geometry = &GeoJSONGeometry{Type: "GeometryCollection", Geometries: []GeoJSONGeometry{point, polygon}}
+	return feature
+}
+
+// newGeoJSONFeatureCollection returns a collection with a feature per info.
+func newGeoJSONFeatureCollection(infos []*conf.IPInfo, circle bool, fields []infoField) *GeoJSONFeatureCollection {
+	collection := &GeoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]GeoJSONFeature, len(infos))}
+
+	for i, info := range infos {
+		collection.Features[i] = *newGeoJSONFeature(info, circle, fields)
+	}
+	return collection
+}
+
+// geoJSONPosition returns longitude and latitude rounded to 6 decimal places (~0.1 m).
+func geoJSONPosition(p geo.Point) []float64 {
+	return []float64{round(p.Longitude, 6), round(p.Latitude, 6)}
+}
+
+func infoPoint(info *conf.IPInfo) geo.Point {
+	return geo.Point{Latitude: info.Latitude, Longitude: info.Longitude}
+}
+
+// kmlDocument is a KML document with placemarks.
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+// kmlPlacemark is a KML placemark, encoding/xml keeps fields order,
+// so Feature elements are before Geometry ones as KML 2.2 schema requires.
+type kmlPlacemark struct { //nolint:govet // fieldalignment, the order is required by KML schema
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Point       *kmlPoint `xml:"Point,omitempty"`
+	Multi       *kmlMulti `xml:"MultiGeometry,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlMulti struct {
+	Point   kmlPoint   `xml:"Point"`
+	Polygon kmlPolygon `xml:"Polygon"`
+}
+
+type kmlPolygon struct {
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+// writeKML writes a KML document with a placemark per info, so it's suitable for several addresses.
+// Placemarks without coordinates have no geometry, their extended data are the fields, nil ones are all infoFields.
+func writeKML(w io.Writer, circle bool, fields []infoField, infos ...*conf.IPInfo) error {
+	if fields == nil {
+		fields = infoFields
+	}
+
+	doc := kmlDocument{Placemarks: make([]kmlPlacemark, len(infos))}
+
+	for i, info := range infos {
+		doc.Placemarks[i] = newKMLPlacemark(info, circle, fields)
+	}
+
+	err := printF(nil, w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err = encoder.Encode(&doc); err != nil {
+		return err
+	}
+
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+	return printF(nil, w, "\n")
+}
+
+func newKMLPlacemark(info *conf.IPInfo, circle bool, fields []infoField) kmlPlacemark {
+	placemark := kmlPlacemark{Name: info.IP, Description: info.Location(), Data: make([]kmlData, len(fields))}
+
+	for i, field := range fields {
+		placemark.Data[i] = kmlData{Name: field.name, Value: formatValue(field.value(info))}
+	}
+
+	if !info.HasCoordinates() {
+		return placemark
+	}
+
+	point := kmlPoint{Coordinates: kmlCoordinates(infoPoint(info))}
+	if !circle || info.AccuracyRadius == 0 {
+		placemark.Point = &point
+		return placemark
+	}
+
+	ring := geo.Circle(infoPoint(info), float64(info.AccuracyRadius), circleSegments)
+	coordinates := make([]string, len(ring))
+
+	for i, p := range ring {
+		coordinates[i] = kmlCoordinates(p)
+	}
+
+	placemark.Multi = &kmlMulti{Point: point, Polygon: kmlPolygon{Coordinates: strings.Join(coordinates, " ")}}
+	return placemark
+}
+
+// kmlCoordinates returns "longitude,latitude" tuple.
+func kmlCoordinates(p geo.Point) string {
+	return formatFloat(round(p.Longitude, 6)) + "," + formatFloat(round(p.Latitude, 6))
+}
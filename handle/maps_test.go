@@ -0,0 +1,308 @@
+package handle
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestGeoJSONHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+	cases := []struct {
+		name     string
+		ip       string
+		query    string
+		accept   string
+		geometry string
+		code     int
+	}{
+		{name: "point", ip: "193.138.218.226", geometry: "Point"},
+		{name: "accept", ip: "193.138.218.226", accept: "application/geo+json, text/html;q=0.5", geometry: "Point"},
+		{name: "not acceptable", ip: "193.138.218.226", accept: "application/json", code: http.StatusNotAcceptable},
+		{name: "circle", ip: "193.138.218.226", query: "?circle=true", geometry: "GeometryCollection"},
+		{name: "no circle", ip: "193.138.218.226", query: "?circle=0", geometry: "Point"},
+		{name: "unknown", ip: "127.0.0.1", query: "?circle=1"},
+		{name: "invalid", ip: "193.138.218.226", query: "?circle=yes", code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/geojson"+c.query, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		if c.accept != "" {
+			req.Header.Add("Accept", c.accept)
+		}
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		err = GeoJSONHandler(w, req, cfg, info)
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: expected status error %d: %v", c.name, c.code, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+		}
+
+		if vary := resp.Header.Get("Vary"); vary != "Accept" {
+			t.Errorf("%s: not equal Vary: %v", c.name, vary)
+		}
+		checkNoCache(t, resp)
+
+		var feature struct {
+			Type       string           `json:"type"`
+			Geometry   *json.RawMessage `json:"geometry"`
+			Properties conf.IPInfo      `json:"properties"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&feature); err != nil {
+			t.Fatal(err)
+		}
+
+		if feature.Type != "Feature" || feature.Properties.IP != c.ip {
+			t.Errorf("%s: unexpected feature %+v", c.name, feature)
+		}
+
+		checkGeometry(t, c.name, feature.Geometry, c.geometry)
+	}
+}
+
+func checkGeometry(t *testing.T, name string, raw *json.RawMessage, geometryType string) {
+	if geometryType == "" {
+		if raw != nil {
+			t.Errorf("%s: geometry is not null: %s", name, *raw)
+		}
+		return
+	}
+
+	if raw == nil {
+		t.Fatalf("%s: geometry is null", name)
+	}
+
+	var geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+		Geometries  []struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometries"`
+	}
+	if err := json.Unmarshal(*raw, &geometry); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	if geometry.Type != geometryType {
+		t.Errorf("%s: not equal geometry type %v != %v", name, geometry.Type, geometryType)
+	}
+
+	if geometryType == "Point" {
+		if expected := []float64{12.9982, 55.6078}; !slices.Equal(geometry.Coordinates, expected) {
+			t.Errorf("%s: not equal coordinates %v != %v", name, geometry.Coordinates, expected)
+		}
+		return
+	}
+
+	if n := len(geometry.Geometries); n != 2 {
+		t.Fatalf("%s: not equal geometries number %d", name, n)
+	}
+
+	var polygon [][][]float64
+	if err := json.Unmarshal(geometry.Geometries[1].Coordinates, &polygon); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	if len(polygon) != 1 || len(polygon[0]) != circleSegments+1 || !slices.Equal(polygon[0][0], polygon[0][circleSegments]) {
+		t.Errorf("%s: invalid polygon %v", name, polygon)
+	}
+}
+
+func TestKMLHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	for _, circle := range []bool{false, true} {
+		url := "https://example.com/kml"
+		if circle {
+			url += "?circle=true"
+		}
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		if err = KMLHandler(w, req, cfg, info); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != "application/vnd.google-earth.kml+xml; charset=utf-8" {
+			t.Errorf("not equal Content-Type: %v", ct)
+		}
+		checkNoCache(t, resp)
+
+		if body := w.Body.String(); !circle && strings.Index(body, "<Point>") < strings.Index(body, "<name>") {
+			t.Errorf("point is before name: %s", body)
+		}
+
+		doc := &kmlDocument{}
+		if err = xml.NewDecoder(resp.Body).Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := len(doc.Placemarks); n != 1 {
+			t.Fatalf("not equal placemarks number %d", n)
+		}
+
+		placemark := doc.Placemarks[0]
+		if placemark.Name != "193.138.218.226" || placemark.Description != "Sweden, Malmo" {
+			t.Errorf("unexpected placemark %+v", placemark)
+		}
+
+		if i := slices.IndexFunc(placemark.Data, func(d kmlData) bool { return d.Name == "asn" }); i < 0 || placemark.Data[i].Value != "39351" {
+			t.Errorf("unexpected extended data %+v", placemark.Data)
+		}
+
+		point := placemark.Point
+		if circle {
+			if placemark.Multi == nil || placemark.Point != nil {
+				t.Fatalf("unexpected geometry %+v", placemark)
+			}
+
+			if n := len(strings.Fields(placemark.Multi.Polygon.Coordinates)); n != circleSegments+1 {
+				t.Errorf("not equal polygon points number %d", n)
+			}
+			point = &placemark.Multi.Point
+		}
+
+		if point == nil || point.Coordinates != "12.9982,55.6078" {
+			t.Errorf("unexpected point %+v", point)
+		}
+	}
+}
+
+func TestMapHandlers_fields(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	req := httptest.NewRequest("GET", "https://example.com/geojson?fields=ip,coords", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err = GeoJSONHandler(w, req, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+
+	var feature struct {
+		Geometry   *json.RawMessage `json:"geometry"`
+		Properties json.RawMessage  `json:"properties"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &feature); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := string(feature.Properties); p != `{"ip":"193.138.218.226","latitude":55.6078,"longitude":12.9982}` {
+		t.Errorf("unexpected properties %s", p)
+	}
+	checkGeometry(t, "geojson fields", feature.Geometry, "Point")
+
+	req = httptest.NewRequest("GET", "https://example.com/kml?fields=city,asn", nil)
+	w = httptest.NewRecorder()
+
+	if err = KMLHandler(w, req, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &kmlDocument{}
+	if err = xml.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []kmlData{{Name: "city", Value: "Malmo"}, {Name: "asn", Value: "39351"}}
+	if len(doc.Placemarks) != 1 || !slices.Equal(doc.Placemarks[0].Data, expected) {
+		t.Errorf("unexpected placemarks %+v", doc.Placemarks)
+	}
+
+	handlers := map[string]func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error{
+		"geojson": GeoJSONHandler,
+		"kml":     KMLHandler,
+	}
+	for name, handler := range handlers {
+		req = httptest.NewRequest("GET", "https://example.com/"+name+"?fields=ip,unknown", nil)
+		var statusErr *StatusError
+
+		if err = handler(httptest.NewRecorder(), req, cfg, info); !errors.As(err, &statusErr) || statusErr.Code != http.StatusBadRequest {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	infos := []*conf.IPInfo{
+		{IP: "193.138.218.226", Country: "Sweden", City: "Malmo", Latitude: 55.6078, Longitude: 12.9982, AccuracyRadius: 20},
+		{IP: "127.0.0.1"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeKML(&buf, true, nil, infos...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2">`) {
+		t.Errorf("unexpected document start: %s", buf.String()[:100])
+	}
+
+	// KML 2.2 schema requires Feature elements before Geometry ones
+	body := buf.String()
+	if name, geometry := strings.Index(body, "<name>"), strings.Index(body, "<MultiGeometry>"); name < 0 || geometry < name {
+		t.Errorf("geometry is before name: %d < %d", geometry, name)
+	}
+
+	if data, geometry := strings.Index(body, "<ExtendedData>"), strings.Index(body, "<MultiGeometry>"); data < 0 || geometry < data {
+		t.Errorf("geometry is before extended data: %d < %d", geometry, data)
+	}
+
+	doc := &kmlDocument{}
+	if err := xml.Unmarshal(buf.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(doc.Placemarks); n != 2 {
+		t.Fatalf("not equal placemarks number %d", n)
+	}
+
+	if doc.Placemarks[0].Multi == nil {
+		t.Error("no geometry of the first placemark")
+	}
+
+	if p := doc.Placemarks[1]; p.Point != nil || p.Multi != nil || p.Name != "127.0.0.1" {
+		t.Errorf("unexpected second placemark %+v", p)
+	}
+}
@@ -0,0 +1,78 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// NearestInfo is a list of points of presence ranked by distance from the client.
+type NearestInfo struct {
+	IP          string           `json:"ip"`
+	CountryCode string           `json:"country_code"`
+	Continent   string           `json:"continent"`
+	PoPs        []conf.RankedPoP `json:"pops"`
+	Located     bool             `json:"located"`
+}
+
+// NearestHandler is handler for application/json response with the nearest points of presence.
+// The number of items can be limited by "limit" parameter. If "redirect" parameter is true,
+// the client is redirected to URL of the nearest PoP instead.
+func NearestHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+	if len(cfg.PoPs) == 0 {
+		return &StatusError{Code: http.StatusNotFound, Err: errors.New("no points of presence")}
+	}
+
+	pops := cfg.Nearest(info)
+
+	if limit := r.FormValue("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid limit %q", limit)}
+		}
+		pops = pops[:min(n, len(pops))]
+	}
+
+	if value := r.FormValue("redirect"); value != "" {
+		redirect, err := strconv.ParseBool(value)
+		if err != nil {
+			return &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("invalid redirect %q", value)}
+		}
+
+		if redirect {
+			return redirectNearest(w, r, pops)
+		}
+	}
+
+	if _, err := acceptOffer(w, r, jsonOffers); err != nil {
+		return err
+	}
+
+	result := NearestInfo{
+		IP:          info.IP,
+		CountryCode: info.CountryCode,
+		Continent:   info.Continent,
+		PoPs:        pops,
+		Located:     info.HasCoordinates(),
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return json.NewEncoder(w).Encode(result)
+}
+
+// redirectNearest redirects to the first PoP which has URL.
+func redirectNearest(w http.ResponseWriter, r *http.Request, pops []conf.RankedPoP) error {
+	for i := range pops {
+		if pops[i].URL != "" {
+			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+			http.Redirect(w, r, pops[i].URL, http.StatusFound)
+			return nil
+		}
+	}
+	return &StatusError{Code: http.StatusNotFound, Err: errors.New("no points of presence with URL")}
+}
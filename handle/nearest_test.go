@@ -0,0 +1,91 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestNearestHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+	pops := []conf.PoP{
+		{Name: "tokyo", URL: "https://jp.example.com", Latitude: 35.68, Longitude: 139.69},
+		{Name: "stockholm", Latitude: 59.33, Longitude: 18.06},
+		{Name: "london", URL: "https://uk.example.com", Latitude: 51.51, Longitude: -0.13},
+	}
+	cases := []struct {
+		name     string
+		ip       string
+		query    string
+		location string
+		pops     []conf.PoP
+		expected []string
+		code     int
+	}{
+		{name: "all", ip: "193.138.218.226", pops: pops, expected: []string{"stockholm", "london", "tokyo"}},
+		{name: "limit", ip: "2001:218::1", query: "?limit=1", pops: pops, expected: []string{"tokyo"}},
+		{name: "big limit", ip: "81.2.69.1", query: "?limit=5&redirect=false", pops: pops, expected: []string{"london", "stockholm", "tokyo"}},
+		{name: "unknown", ip: "127.0.0.1", pops: pops, expected: []string{"tokyo", "stockholm", "london"}},
+		{name: "redirect", ip: "193.138.218.226", query: "?redirect=true", pops: pops, location: "https://uk.example.com"},
+		{name: "redirect no url", ip: "193.138.218.226", query: "?redirect=1", pops: pops[1:2], code: http.StatusNotFound},
+		{name: "bad limit", ip: "193.138.218.226", query: "?limit=0", pops: pops, code: http.StatusBadRequest},
+		{name: "bad redirect", ip: "193.138.218.226", query: "?redirect=maybe", pops: pops, code: http.StatusBadRequest},
+		{name: "no pops", ip: "193.138.218.226", code: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		cfg.PoPs = c.pops
+
+		req := httptest.NewRequest("GET", "https://example.com/nearest"+c.query, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		err = NearestHandler(w, req, cfg, info)
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		resp := w.Result()
+		checkNoCache(t, resp)
+
+		if c.location != "" {
+			if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != c.location {
+				t.Errorf("%s: unexpected redirect %d %v", c.name, resp.StatusCode, resp.Header.Get("Location"))
+			}
+			continue
+		}
+
+		result := NearestInfo{}
+		if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result.IP != c.ip || result.Located != (c.ip != "127.0.0.1") || len(result.PoPs) != len(c.expected) {
+			t.Errorf("%s: unexpected result %v", c.name, result)
+			continue
+		}
+
+		for i, name := range c.expected {
+			if result.PoPs[i].Name != name {
+				t.Errorf("%s: not equal %d name %v != %v", c.name, i, result.PoPs[i].Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package handle
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// mediaOffer is a negotiated response format, format is a key of fieldsEncoders or batchFormats.
+// Offers of handlers with a single response format have no handler.
+type mediaOffer struct {
+	handler   func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error
+	mediaType string
+	format    string
+}
+
+// infoOffer converts a handler of info formats to mediaOffer one.
+func infoOffer(mediaType, format string, h func(http.ResponseWriter, *conf.IPInfo, *BuildInfo) error) mediaOffer {
+	return mediaOffer{mediaType: mediaType, format: format, handler: infoHandler(h)}
+}
+
+// infoHandler converts a handler of info formats to request handler.
+func infoHandler(h func(http.ResponseWriter, *conf.IPInfo, *BuildInfo) error) func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error {
+	return func(w http.ResponseWriter, _ *http.Request, _ *conf.Cfg, info *conf.IPInfo) error {
+		return h(w, info, nil)
+	}
+}
+
+// mediaOffers are formats of NegotiateHandler, the order is server preference for equal quality values.
+var mediaOffers = []mediaOffer{ //nolint:gochecknoglobals
+	{mediaType: "text/plain", format: "text", handler: TextHandler},
+	infoOffer("text/html", "html", HTMLHandler),
+	infoOffer("application/json", "json", JSONHandler),
+	infoOffer("application/xml", "xml", XMLHandler),
+	infoOffer("text/xml", "xml", XMLHandler),
+	infoOffer("application/yaml", "yaml", YAMLHandler),
+	infoOffer("application/x-yaml", "yaml", YAMLHandler),
+	infoOffer("text/yaml", "yaml", YAMLHandler),
+	infoOffer("text/csv", "csv", CSVHandler),
+	infoOffer("text/markdown", "markdown", MarkdownHandler),
+	infoOffer("application/msgpack", "msgpack", MsgPackHandler),
+	infoOffer("application/x-msgpack", "msgpack", MsgPackHandler),
+	infoOffer("application/cbor", "cbor", CBORHandler),
+	infoOffer("application/x-protobuf", "protobuf", ProtobufHandler),
+	infoOffer("application/protobuf", "protobuf", ProtobufHandler),
+}
+
+// jsonOffers are formats of JSON only handlers.
+var jsonOffers = []mediaOffer{{mediaType: "application/json", format: "json"}} //nolint:gochecknoglobals
+
+// outputHandlers are handlers of the default route by "output" parameter, it overrides negotiation.
+var outputHandlers = map[string]func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error{ //nolint:gochecknoglobals
+	"text":     TextHandler,
+	"short":    infoHandler(TextShortHandler),
+	"compact":  infoHandler(TextCompactHandler),
+	"html":     infoHandler(HTMLHandler),
+	"full":     infoHandler(FullHTMLHandler),
+	"json":     infoHandler(JSONHandler),
+	"xml":      infoHandler(XMLHandler),
+	"yaml":     infoHandler(YAMLHandler),
+	"csv":      infoHandler(CSVHandler),
+	"msgpack":  infoHandler(MsgPackHandler),
+	"cbor":     infoHandler(CBORHandler),
+	"protobuf": infoHandler(ProtobufHandler),
+	"shell":    infoHandler(ShellHandler),
+	"env":      infoHandler(EnvHandler),
+	"markdown": infoHandler(MarkdownHandler),
+}
+
+// mediaRange is a parsed item of Accept header.
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	quality   float64
+}
+
+// specificity returns 2 for exact media type, 1 for "type/*" and 0 for "*/*", or -1 if the range doesn't match.
+func (m *mediaRange) specificity(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	switch {
+	case m.mediaType == "*" && m.subtype == "*":
+		return 0
+	case m.mediaType != typ:
+		return -1
+	case m.subtype == "*":
+		return 1
+	case m.subtype == subtype:
+		return 2
+	}
+	return -1
+}
+
+// NegotiateHandler is handler for the default route, its response format is selected by Accept header
+// with quality values. Plain text is returned if there is no header, "406 Not Acceptable" if no format matches.
+// If the header has no explicit media type, command line tools get compact text, and browsers always get full HTML
+// instead of the short one. Clients are detected by User-Agent header, "output" parameter overrides all of it.
+// Requests with "fields" parameter are handled by FieldsHandler.
+func NegotiateHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+	if r.FormValue("fields") != "" {
+		return FieldsHandler(w, r, cfg, info)
+	}
+
+	if output := r.FormValue("output"); output != "" {
+		h, ok := outputHandlers[output]
+		if !ok {
+			return &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("unknown output %q", output)}
+		}
+		return h(w, r, cfg, info)
+	}
+
+	w.Header().Set("Vary", "Accept, User-Agent")
+
+	offer, explicit, ok := negotiate(r.Header.Values("Accept"), mediaOffers)
+	if !ok {
+		return notAcceptableError(mediaOffers)
+	}
+
+	switch client := cfg.UserAgents.Client(r.UserAgent()); {
+	case client == conf.ClientCLI && !explicit:
+		return TextCompactHandler(w, info, nil)
+	case client == conf.ClientBrowser && offer.format == "html":
+		return FullHTMLHandler(w, info, nil)
+	}
+	return offer.handler(w, r, cfg, info)
+}
+
+// acceptOffer returns the best offer for Accept header or "406 Not Acceptable" error, it adds Vary header.
+// The first offer is returned if there is no header.
+func acceptOffer(w http.ResponseWriter, r *http.Request, offers []mediaOffer) (*mediaOffer, error) {
+	w.Header().Add("Vary", "Accept")
+
+	offer, _, ok := negotiate(r.Header.Values("Accept"), offers)
+	if !ok {
+		return nil, notAcceptableError(offers)
+	}
+	return offer, nil
+}
+
+// notAcceptableError returns "406 Not Acceptable" error with supported media types.
+func notAcceptableError(offers []mediaOffer) error {
+	mediaTypes := make([]string, len(offers))
+	for i := range offers {
+		mediaTypes[i] = offers[i].mediaType
+	}
+
+	err := fmt.Errorf("not acceptable, supported media types: %s", strings.Join(mediaTypes, ", "))
+	return &StatusError{Code: http.StatusNotAcceptable, Err: err}
+}
+
+// negotiate returns the best offer for Accept header values and true if it's matched by an explicit media range.
+// Offers are compared by quality and then by specificity of matched media range,
+// so "application/json, */*" selects JSON.
+func negotiate(values []string, offers []mediaOffer) (*mediaOffer, bool, bool) {
+	ranges := parseAccept(values)
+	if len(ranges) == 0 {
+		return &offers[0], false, true
+	}
+
+	var (
+		best        *mediaOffer
+		bestQuality float64
+		bestSpec    = -1
+	)
+
+	for i := range offers {
+		quality, spec := offerQuality(ranges, offers[i].mediaType)
+		if quality <= 0 {
+			continue
+		}
+
+		if quality > bestQuality || (quality == bestQuality && spec > bestSpec) {
+			best, bestQuality, bestSpec = &offers[i], quality, spec
+		}
+	}
+	return best, bestSpec > 0, best != nil
+}
+
+// offerQuality returns quality and specificity of the most specific range matched media type.
+func offerQuality(ranges []mediaRange, mediaType string) (float64, int) {
+	var (
+		quality float64
+		spec    = -1
+	)
+
+	for i := range ranges {
+		if s := ranges[i].specificity(mediaType); s > spec {
+			quality, spec = ranges[i].quality, s
+		}
+	}
+	return quality, spec
+}
+
+// parseAccept parses Accept header values, invalid ranges are skipped.
+func parseAccept(values []string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			params := strings.Split(item, ";")
+			mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+			typ, subtype, found := strings.Cut(mediaType, "/")
+			if !found || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+				continue
+			}
+
+			quality, ok := rangeQuality(params[1:])
+			if !ok {
+				continue
+			}
+
+			ranges = append(ranges, mediaRange{mediaType: typ, subtype: subtype, quality: quality})
+		}
+	}
+	return ranges
+}
+
+// rangeQuality returns "q" parameter value, it's 1 by default.
+func rangeQuality(params []string) (float64, bool) {
+	for _, param := range params {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(name, "q") {
+			continue
+		}
+
+		quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || quality < 0 || quality > 1 {
+			return 0, false
+		}
+		return quality, true
+	}
+	return 1, true
+}
@@ -0,0 +1,164 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	cases := []struct {
+		name        string
+		contentType string
+		accept      []string
+		code        int
+	}{
+		{name: "no header", contentType: "text/plain; charset=utf-8"},
+		{name: "any", accept: []string{"*/*"}, contentType: "text/plain; charset=utf-8"},
+		{name: "curl json", accept: []string{"application/json"}, contentType: "application/json; charset=utf-8"},
+		{name: "json and any", accept: []string{"application/json, */*"}, contentType: "application/json; charset=utf-8"},
+		{
+			name:        "browser",
+			accept:      []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			contentType: "text/html; charset=utf-8",
+		},
+		{name: "quality", accept: []string{"application/json;q=0.5, application/xml"}, contentType: "application/xml; charset=utf-8"},
+		{name: "text xml", accept: []string{"text/xml"}, contentType: "application/xml; charset=utf-8"},
+		{name: "several headers", accept: []string{"image/png;q=1", "text/csv;q=0.2"}, contentType: "text/csv; charset=utf-8"},
+		{name: "type range", accept: []string{"application/*;q=0.9, application/json;q=0"}, contentType: "application/xml; charset=utf-8"},
+		{name: "excluded text", accept: []string{"text/plain;q=0, */*"}, contentType: "text/html; charset=utf-8"},
+		{name: "yaml", accept: []string{"application/yaml"}, contentType: "application/yaml; charset=utf-8"},
+		{name: "markdown", accept: []string{"text/markdown"}, contentType: "text/markdown; charset=utf-8"},
+		{name: "binary", accept: []string{"application/x-msgpack"}, contentType: "application/msgpack"},
+		{name: "protobuf", accept: []string{"application/protobuf; proto=ipinfo.v1.IPInfo"}, contentType: "application/x-protobuf"},
+		{name: "case", accept: []string{"Application/JSON; Q=1"}, contentType: "application/json; charset=utf-8"},
+		{name: "bad quality", accept: []string{"application/json;q=2, text/csv"}, contentType: "text/csv; charset=utf-8"},
+		{name: "not acceptable", accept: []string{"image/png"}, code: http.StatusNotAcceptable},
+		{name: "zero quality", accept: []string{"*/*;q=0"}, code: http.StatusNotAcceptable},
+		{name: "invalid", accept: []string{"json, */json"}, contentType: "text/plain; charset=utf-8"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		for _, accept := range c.accept {
+			req.Header.Add("Accept", accept)
+		}
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		err = NegotiateHandler(w, req, cfg, info)
+
+		if vary := w.Header().Get("Vary"); vary != "Accept, User-Agent" {
+			t.Errorf("%s: not equal Vary header: %q", c.name, vary)
+		}
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: expected status error %d: %v", c.name, c.code, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		if ct := w.Result().Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s: not equal Content-Type %v != %v", c.name, ct, c.contentType)
+		}
+	}
+}
+
+func TestNegotiateHandlerUserAgent(t *testing.T) {
+	cfg := newTestCfg(t)
+
+	const (
+		browser    = "Mozilla/5.0 (X11; Linux x86_64; rv:140.0) Gecko/20100101 Firefox/140.0"
+		powerShell = "Mozilla/5.0 (Windows NT 10.0; Microsoft Windows 10.0.19045; en-US) PowerShell/7.5.2"
+	)
+	cases := []struct {
+		name        string
+		userAgent   string
+		accept      string
+		url         string
+		contentType string
+		body        string // substring of the response
+		code        int
+	}{
+		{name: "curl", userAgent: "curl/8.14.1", accept: "*/*", contentType: "text/plain; charset=utf-8", body: "Sweden Malmo\n"},
+		{name: "wget", userAgent: "Wget/1.25.0", contentType: "text/plain; charset=utf-8", body: "Sweden Malmo\n"},
+		{name: "httpie", userAgent: "HTTPie/3.2.4", accept: "application/json, */*;q=0.5", contentType: "application/json; charset=utf-8"},
+		{name: "powershell", userAgent: powerShell, contentType: "text/plain; charset=utf-8", body: "Sweden Malmo\n"},
+		{name: "curl json", userAgent: "curl/8.14.1", accept: "application/json", contentType: "application/json; charset=utf-8"},
+		{
+			name:        "browser",
+			userAgent:   browser,
+			accept:      "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			contentType: "text/html; charset=utf-8",
+			body:        "pico.min.css",
+		},
+		{name: "browser any", userAgent: browser, accept: "*/*", contentType: "text/plain; charset=utf-8", body: "Headers"},
+		{name: "unknown", userAgent: "Go-http-client/1.1", contentType: "text/plain; charset=utf-8", body: "Headers"},
+		{name: "override text", userAgent: "curl/8.14.1", url: "/?output=text", contentType: "text/plain; charset=utf-8", body: "Headers"},
+		{name: "override json", userAgent: browser, url: "/?output=json", accept: "text/html", contentType: "application/json; charset=utf-8"},
+		{name: "override fields", userAgent: browser, url: "/?output=yaml&fields=ip", contentType: "application/yaml; charset=utf-8", body: "ip: "},
+		{name: "override shell", userAgent: browser, url: "/?output=shell", contentType: "text/plain; charset=utf-8", body: "export IPINFO_CITY='Malmo'\n"},
+		{name: "override env fields", url: "/?output=env&fields=city", contentType: "text/plain; charset=utf-8", body: "IPINFO_CITY=\"Malmo\"\n"},
+		{name: "unknown output", userAgent: browser, url: "/?output=pdf", code: http.StatusBadRequest},
+		{name: "unknown fields output", url: "/?output=pdf&fields=ip", code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		url := c.url
+		if url == "" {
+			url = "/"
+		}
+
+		req := httptest.NewRequest("GET", "https://example.com"+url, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+		req.Header.Set("User-Agent", c.userAgent)
+
+		if c.accept != "" {
+			req.Header.Add("Accept", c.accept)
+		}
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		err = NegotiateHandler(w, req, cfg, info)
+
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: expected status error %d: %v", c.name, c.code, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		if ct := w.Result().Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s: not equal Content-Type %v != %v", c.name, ct, c.contentType)
+		}
+
+		if body := w.Body.String(); !strings.Contains(body, c.body) {
+			t.Errorf("%s: body doesn't contain %q: %s", c.name, c.body, body)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package handle
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// jsonSchema is a JSON schema object of OpenAPI 3.1 document.
+type jsonSchema = map[string]any
+
+// openAPIDocument is OpenAPI 3.1 document of the service.
+type openAPIDocument struct {
+	Info       openAPIInfo                 `json:"info"`
+	Paths      map[string]*openAPIPathItem `json:"paths"`
+	Components openAPIComponents           `json:"components"`
+	OpenAPI    string                      `json:"openapi"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]jsonSchema `json:"schemas"`
+}
+
+type openAPIPathItem struct {
+	Get *openAPIOperation `json:"get"`
+}
+
+type openAPIOperation struct {
+	Responses  map[string]*openAPIResponse `json:"responses"`
+	Summary    string                      `json:"summary"`
+	Parameters []openAPIParameter          `json:"parameters,omitempty"`
+}
+
+type openAPIParameter struct {
+	Schema      jsonSchema `json:"schema"`
+	Name        string     `json:"name"`
+	In          string     `json:"in"`
+	Description string     `json:"description"`
+	Required    bool       `json:"required,omitempty"`
+}
+
+type openAPIResponse struct {
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	Description string                      `json:"description"`
+}
+
+type openAPIMediaType struct {
+	Schema jsonSchema `json:"schema"`
+}
+
+// OpenAPIHandler is handler for application/json response with OpenAPI document of all routes.
+func OpenAPIHandler(w http.ResponseWriter, _ *conf.IPInfo, buildInfo *BuildInfo) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	version := ""
+	if buildInfo != nil {
+		version = buildInfo.Version
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(newOpenAPIDocument(version)); err != nil {
+		return fmt.Errorf("OpenAPIHandler: %w", err)
+	}
+	return nil
+}
+
+// newOpenAPIDocument describes routes, response schemas are built by types of handlers' responses.
+func newOpenAPIDocument(version string) *openAPIDocument {
+	var (
+		s      = &schemaBuilder{schemas: make(map[string]jsonSchema)}
+		info   = s.schema(reflect.TypeFor[conf.IPInfo]())
+		str    = jsonSchema{"type": "string"}
+		text   = contentOf(str, "text/plain")
+		binary = jsonSchema{"type": "string", "format": "binary"}
+	)
+
+	var v2Response jsonSchema
+	if err := json.Unmarshal(v2Schema, &v2Response); err != nil {
+		panic(err) // embedded schema is checked by tests
+	}
+	s.schemas["V2Response"] = v2Response
+
+	var (
+		v2       = contentOf(jsonSchema{"$ref": "#/components/schemas/V2Response"}, "application/json")
+		circle   = queryParameter("circle", "Add accuracy radius polygon if it's true")
+		redirect = &openAPIOperation{
+			Summary:   "Redirect to the target of the named geo redirect rules",
+			Responses: map[string]*openAPIResponse{},
+		}
+	)
+	redirect.addResponse(http.StatusFound, nil)
+	redirect.addResponse(http.StatusNotFound, text)
+
+	paths := map[string]*openAPIOperation{
+		"/": newOperation("Client's info in a format negotiated by Accept and User-Agent headers", negotiatedContent(info, binary),
+			http.StatusNotAcceptable).withParameters(outputParameter()),
+		"/short":        newOperation("Short text info", text),
+		"/compact":      newOperation("Compact text info", text),
+		"/json":         newOperation("JSON info", contentOf(info, "application/json")),
+		"/xml":          newOperation("XML info with the same elements as JSON keys", contentOf(str, "application/xml")),
+		"/yaml":         newOperation("YAML info", contentOf(info, "application/yaml")),
+		"/csv":          newOperation("CSV header and values", contentOf(str, "text/csv")),
+		"/msgpack":      newOperation("MessagePack info", contentOf(info, "application/msgpack")),
+		"/cbor":         newOperation("CBOR info", contentOf(info, "application/cbor")),
+		"/protobuf":     newOperation("Protocol Buffers IPInfo message", contentOf(binary, "application/x-protobuf")),
+		"/ipinfo.proto": newOperation("Protocol Buffers schema", text),
+		"/shell":        newOperation("POSIX shell export commands", text),
+		"/env":          newOperation(".env file variables", text),
+		"/markdown":     newOperation("Markdown table", contentOf(str, "text/markdown")),
+		"/html":         newOperation("HTML page", contentOf(str, "text/html")),
+		"/full":         newOperation("Full HTML page", contentOf(str, "text/html")),
+		"/version":      newOperation("Service version", text),
+		"/openapi.json": newOperation("OpenAPI document", contentOf(jsonSchema{"type": "object"}, "application/json")),
+		"/ip":           newOperation("Client IP address", text),
+		"/country":      newOperation("Country name", text),
+		"/city":         newOperation("City name", text),
+		"/tz":           newOperation("Time zone", text),
+		"/coords":       newOperation("Comma separated latitude and longitude", text),
+		"/time": newOperation("Local time in several formats", text, http.StatusBadRequest).withParameters(
+			queryParameter("at", "Instant to convert, RFC3339 or Unix seconds"),
+			queryParameter("tz", "IANA time zone name"),
+			queryParameter("format", "Single value format", timeFormatNames()...),
+		),
+		"/distance": newOperation("Distance and bearing between two points",
+			contentOf(s.schema(reflect.TypeFor[DistanceInfo]()), "application/json"), http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable,
+		).withParameters(
+			queryParameter("from", "Start IP address, the default is the client one"),
+			queryParameter("to", "Target IP address"),
+			queryParameter("lat", "Target latitude"),
+			queryParameter("lon", "Target longitude"),
+		),
+		"/nearest": newOperation("Points of presence ranked by distance",
+			contentOf(s.schema(reflect.TypeFor[NearestInfo]()), "application/json"), http.StatusFound, http.StatusBadRequest, http.StatusNotFound,
+			http.StatusNotAcceptable,
+		).withParameters(
+			queryParameter("limit", "Maximum number of items"),
+			queryParameter("redirect", "Redirect to URL of the nearest PoP if it's true"),
+		),
+		"/auth": newOperation("External authentication for reverse proxies", text, http.StatusForbidden),
+		"/geojson": newOperation("GeoJSON feature of the location",
+			contentOf(s.schema(reflect.TypeFor[GeoJSONFeature]()), "application/geo+json"), http.StatusBadRequest, http.StatusNotAcceptable,
+		).withParameters(circle, fieldsParameter()),
+		"/kml": newOperation("KML document of the location",
+			contentOf(str, "application/vnd.google-earth.kml+xml"), http.StatusBadRequest, http.StatusNotAcceptable,
+		).withParameters(circle, fieldsParameter()),
+		"/batch": newOperation("Lookup of several IP addresses",
+			batchContent(info, s.schema(reflect.TypeFor[GeoJSONFeatureCollection]())), http.StatusBadRequest, http.StatusNotAcceptable,
+		).withParameters(
+			queryParameter("ip", "Comma or space separated IP addresses, the parameter can be repeated"),
+			queryParameter("format", "Response format, it overrides Accept header", batchFormatNames()...),
+			circle,
+			fieldsParameter(),
+		),
+		RedirectPrefix + "{name}": redirect.withParameters(
+			openAPIParameter{Name: "name", In: "path", Required: true, Description: "Name of redirect rules", Schema: str},
+		),
+		V2Prefix:                  newOperation("API v2 client's info", v2),
+		V2Prefix + "/schema.json": newOperation("JSON schema of API v2 responses", contentOf(jsonSchema{"type": "object"}, "application/schema+json")),
+	}
+
+	for path, op := range paths {
+		if _, ok := fieldsPaths[path]; ok || path == "/" {
+			op.withParameters(fieldsParameter()).addResponse(http.StatusBadRequest, text)
+		}
+	}
+
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       "IPInfo",
+			Description: "IP info web service. Paths of user-defined templates are set by the configuration and aren't listed.",
+			Version:     version,
+		},
+		Paths:      make(map[string]*openAPIPathItem, len(paths)),
+		Components: openAPIComponents{Schemas: s.schemas},
+	}
+
+	for path, op := range paths {
+		doc.Paths[path] = &openAPIPathItem{Get: op}
+	}
+	return doc
+}
+
+// newOperation returns an operation with the successful response content and responses of other status codes,
+// content of error responses is plain text.
+func newOperation(summary string, content map[string]openAPIMediaType, codes ...int) *openAPIOperation {
+	op := &openAPIOperation{
+		Summary:   summary,
+		Responses: map[string]*openAPIResponse{"200": {Description: http.StatusText(http.StatusOK), Content: content}},
+	}
+
+	for _, code := range codes {
+		if code == http.StatusFound {
+			op.addResponse(code, nil)
+		} else {
+			op.addResponse(code, contentOf(jsonSchema{"type": "string"}, "text/plain"))
+		}
+	}
+	return op
+}
+
+// withParameters adds parameters to the operation and returns it.
+func (op *openAPIOperation) withParameters(parameters ...openAPIParameter) *openAPIOperation {
+	op.Parameters = append(op.Parameters, parameters...)
+	return op
+}
+
+// addResponse adds a response of the status code if it's not described yet.
+func (op *openAPIOperation) addResponse(code int, content map[string]openAPIMediaType) {
+	key := strconv.Itoa(code)
+	if _, ok := op.Responses[key]; !ok {
+		op.Responses[key] = &openAPIResponse{Description: http.StatusText(code), Content: content}
+	}
+}
+
+// contentOf returns content of media types with the same schema.
+func contentOf(schema jsonSchema, mediaTypes ...string) map[string]openAPIMediaType {
+	content := make(map[string]openAPIMediaType, len(mediaTypes))
+
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = openAPIMediaType{Schema: schema}
+	}
+	return content
+}
+
+// negotiatedContent returns content of all negotiated media types, JSON compatible formats have info schema.
+func negotiatedContent(info, binary jsonSchema) map[string]openAPIMediaType {
+	content := make(map[string]openAPIMediaType, len(mediaOffers))
+
+	for _, offer := range mediaOffers {
+		switch offer.format {
+		case "json", "yaml", "msgpack", "cbor":
+			content[offer.mediaType] = openAPIMediaType{Schema: info}
+		case "protobuf":
+			content[offer.mediaType] = openAPIMediaType{Schema: binary}
+		default:
+			content[offer.mediaType] = openAPIMediaType{Schema: jsonSchema{"type": "string"}}
+		}
+	}
+	return content
+}
+
+// batchContent returns content of all batch formats, JSON one is an array of info.
+func batchContent(info, collection jsonSchema) map[string]openAPIMediaType {
+	content := make(map[string]openAPIMediaType, len(batchFormats))
+
+	for name, format := range batchFormats {
+		mediaType, _, _ := mime.ParseMediaType(format.contentType)
+		switch name {
+		case "json":
+			content[mediaType] = openAPIMediaType{Schema: jsonSchema{"type": "array", "items": info}}
+		case "geojson":
+			content[mediaType] = openAPIMediaType{Schema: collection}
+		default:
+			content[mediaType] = openAPIMediaType{Schema: jsonSchema{"type": "string"}}
+		}
+	}
+	return content
+}
+
+// batchFormatNames returns sorted names of batchFormats.
+func batchFormatNames() []string {
+	return slices.Sorted(maps.Keys(batchFormats))
+}
+
+func queryParameter(name, description string, values ...string) openAPIParameter {
+	schema := jsonSchema{"type": "string"}
+	if len(values) > 0 {
+		schema["enum"] = values
+	}
+	return openAPIParameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// timeFormatNames returns names of timeFormats.
+func timeFormatNames() []string {
+	names := make([]string, len(timeFormats))
+
+	for i, f := range timeFormats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// fieldsParameter returns "fields" parameter with names of infoFields and aliases.
+func fieldsParameter() openAPIParameter {
+	names := make([]string, 0, len(infoFields)+len(fieldAliases))
+
+	for _, field := range infoFields {
+		names = append(names, field.name)
+	}
+
+	for alias := range fieldAliases {
+		names = append(names, alias)
+	}
+
+	slices.Sort(names[len(infoFields):])
+	return openAPIParameter{
+		Name:        "fields",
+		In:          "query",
+		Description: "Comma separated attributes: " + strings.Join(names, ", "),
+		Schema:      jsonSchema{"type": "string"},
+	}
+}
+
+// outputParameter returns "output" parameter with names of outputHandlers.
+func outputParameter() openAPIParameter {
+	names := make([]string, 0, len(outputHandlers))
+
+	for name := range outputHandlers {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return queryParameter("output", "Response format, it overrides Accept header", names...)
+}
+
+// schemaBuilder builds JSON schemas of types by their JSON encoding, named structs are components.
+type schemaBuilder struct {
+	schemas map[string]jsonSchema
+}
+
+// schema returns a schema of the type, a named struct is added to components and referenced.
+func (s *schemaBuilder) schema(t reflect.Type) jsonSchema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+
+		if _, ok := s.schemas[t.Name()]; !ok {
+			s.schemas[t.Name()] = nil // recursive types are referenced
+			s.schemas[t.Name()] = s.object(t)
+		}
+		return jsonSchema{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return jsonSchema{} // any value
+	}
+}
+
+// object returns a schema of struct properties, fields without omitempty option are required
+// and pointers of them are nullable. Embedded structs without names are flattened.
+func (s *schemaBuilder) object(t reflect.Type) jsonSchema {
+	properties, required := make(map[string]any), []string{}
+
+	for field := range t.Fields() {
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := s.object(field.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			required = append(required, embedded["required"].([]string)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schema(field.Type)
+		omitEmpty := slices.Contains(strings.Split(options, ","), "omitempty")
+
+		if !omitEmpty {
+			required = append(required, name)
+			if field.Type.Kind() == reflect.Pointer {
+				schema = jsonSchema{"anyOf": []any{schema, jsonSchema{"type": "null"}}}
+			}
+		}
+		properties[name] = schema
+	}
+
+	slices.Sort(required)
+	return jsonSchema{"type": "object", "properties": properties, "required": required}
+}
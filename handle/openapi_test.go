@@ -0,0 +1,93 @@
+package handle
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	s := &schemaBuilder{schemas: make(map[string]jsonSchema)}
+
+	if ref := s.schema(reflect.TypeFor[GeoJSONFeature]()); ref["$ref"] != "#/components/schemas/GeoJSONFeature" {
+		t.Errorf("unexpected reference %v", ref)
+	}
+
+	for _, name := range []string{"GeoJSONFeature", "GeoJSONGeometry", "IPInfo", "Sources"} {
+		if s.schemas[name] == nil {
+			t.Errorf("schema %q is not built", name)
+		}
+	}
+
+	info := s.schemas["IPInfo"]
+	properties := info["properties"].(map[string]any)
+	required := info["required"].([]string)
+
+	for _, name := range []string{"ip", "utc_offset_seconds", "is_dst", "accuracy_radius"} {
+		if _, ok := properties[name]; !ok || !slices.Contains(required, name) {
+			t.Errorf("property %q is not required", name)
+		}
+	}
+
+	for _, name := range []string{"asn", "as_organization", "sources"} {
+		if _, ok := properties[name]; !ok || slices.Contains(required, name) {
+			t.Errorf("property %q is not optional", name)
+		}
+	}
+
+	for _, name := range []string{"Timestamp", "Addr", "TimeZoneInfo"} {
+		if _, ok := properties[name]; ok {
+			t.Errorf("unexpected property %q", name)
+		}
+	}
+
+	if n := len(properties); n != len(infoFields)+1 {
+		t.Errorf("not equal number of properties %d != %d", n, len(infoFields)+1)
+	}
+
+	geometry := s.schemas["GeoJSONFeature"]["properties"].(map[string]any)["geometry"].(jsonSchema)
+	if _, ok := geometry["anyOf"]; !ok {
+		t.Errorf("geometry is not nullable: %v", geometry)
+	}
+
+	items := s.schemas["GeoJSONGeometry"]["properties"].(map[string]any)["geometries"].(jsonSchema)["items"].(jsonSchema)
+	if items["$ref"] != "#/components/schemas/GeoJSONGeometry" {
+		t.Errorf("unexpected recursive items %v", items)
+	}
+
+	if schema := s.schema(reflect.TypeFor[conf.TimeZoneInfo]()); schema["$ref"] != "#/components/schemas/TimeZoneInfo" {
+		t.Errorf("unexpected named struct schema %v", schema)
+	}
+}
+
+func TestOpenAPIParameters(t *testing.T) {
+	doc := newOpenAPIDocument("v1")
+
+	for path := range fieldsPaths {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("fields path %q is not documented", path)
+			continue
+		}
+
+		if !slices.ContainsFunc(item.Get.Parameters, func(p openAPIParameter) bool { return p.Name == "fields" }) {
+			t.Errorf("%s: no fields parameter", path)
+		}
+	}
+
+	root := doc.Paths["/"].Get
+	i := slices.IndexFunc(root.Parameters, func(p openAPIParameter) bool { return p.Name == "output" })
+	if i < 0 {
+		t.Fatal("no output parameter")
+	}
+
+	if values := root.Parameters[i].Schema["enum"].([]string); len(values) != len(outputHandlers) {
+		t.Errorf("not equal output values %v", values)
+	}
+
+	if content := root.Responses["200"].Content; len(content) != len(mediaOffers) {
+		t.Errorf("not equal negotiated media types %d != %d", len(content), len(mediaOffers))
+	}
+}
@@ -0,0 +1,31 @@
+package handle
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// RedirectPrefix is URL path prefix of named geo redirects.
+const RedirectPrefix = "/go/"
+
+// RedirectHandler redirects the client to target URL of the named redirect,
+// the name is the rest of URL path after RedirectPrefix.
+func RedirectHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, RedirectPrefix), "/ ")
+
+	target, ok := cfg.Redirect(name, info)
+	if !ok {
+		return &StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("unknown redirect %q", name)}
+	}
+
+	if target == "" {
+		return &StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("no target of redirect %q", name)}
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	http.Redirect(w, r, target, http.StatusFound)
+	return nil
+}
@@ -0,0 +1,85 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	const configName = "redirects.json"
+	config := `{
+		"ip_header": "X-Real-Ip",
+		"db": "` + mmdbtest.DBName + `",
+		"redirects": {
+			"download": {
+				"default": "https://example.com/download",
+				"rules": [{"countries": ["SE"], "target": "https://se.example.com/download"}]
+			},
+			"docs": {"rules": [{"continents": ["AS"], "target": "https://asia.example.com/docs"}]}
+		}
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := conf.New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cases := []struct {
+		path     string
+		ip       string
+		location string
+		code     int
+	}{
+		{path: "/go/download", ip: "193.138.218.226", location: "https://se.example.com/download"},
+		{path: "/go/download/", ip: "81.2.69.1", location: "https://example.com/download"},
+		{path: "/go/docs", ip: "2001:218::1", location: "https://asia.example.com/docs"},
+		{path: "/go/docs", ip: "193.138.218.226", code: http.StatusNotFound},
+		{path: "/go/unknown", ip: "193.138.218.226", code: http.StatusNotFound},
+		{path: "/go/", ip: "193.138.218.226", code: http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.path, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w := httptest.NewRecorder()
+		err = RedirectHandler(w, req, cfg, info)
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s %s: unexpected error: %v", c.path, c.ip, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", c.path, c.ip, err)
+			continue
+		}
+
+		resp := w.Result()
+		checkNoCache(t, resp)
+
+		if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != c.location {
+			t.Errorf("%s %s: unexpected redirect %d %v", c.path, c.ip, resp.StatusCode, resp.Header.Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package handle
+
+import (
+	"net/http"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// TemplateHandler is handler for user-defined template response, its content type is set by the configuration.
+func TemplateHandler(w http.ResponseWriter, t *conf.Template, info *conf.IPInfo) error {
+	w.Header().Set("Content-Type", t.ContentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return t.Execute(w, info)
+}
@@ -0,0 +1,97 @@
+package handle
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+func TestTemplateHandler(t *testing.T) {
+	files := map[string]string{
+		"vars.tmpl":   "IPINFO_IP={{ .IP }}\nIPINFO_COUNTRY={{ .CountryCode }}\n",
+		"city.html":   "<b>{{ .City }}</b>",
+		"broken.tmpl": "{{ .IP }} {{ .Unknown }}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const configName = "templates.json"
+	config := `{
+		"ip_header": "X-Real-Ip",
+		"db": "` + mmdbtest.DBName + `",
+		"templates": [
+			{"path": "/vars", "file": "vars.tmpl", "content_type": "text/x-shellscript"},
+			{"path": "/city.html", "file": "city.html", "html": true},
+			{"path": "/broken", "file": "broken.tmpl"}
+		]
+	}`
+	if err := os.WriteFile(configName, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := conf.New(configName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	cases := []struct {
+		path        string
+		contentType string
+		expected    string
+		err         bool
+	}{
+		{path: "/vars", contentType: "text/x-shellscript", expected: "IPINFO_IP=193.138.218.226\nIPINFO_COUNTRY=SE\n"},
+		{path: "/city.html", contentType: "text/html; charset=utf-8", expected: "<b>Malmo</b>"},
+		{path: "/broken", err: true},
+	}
+	for _, c := range cases {
+		tmpl, found := cfg.Template(c.path)
+		if !found {
+			t.Fatalf("%s: template not found", c.path)
+		}
+
+		req := httptest.NewRequest("GET", "https://example.com"+c.path, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w := httptest.NewRecorder()
+		err = TemplateHandler(w, tmpl, info)
+
+		if c.err {
+			if err == nil || w.Body.Len() > 0 {
+				t.Errorf("%s: expected error without body: %v %q", c.path, err, w.Body.String())
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.path, err)
+			continue
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s: not equal Content-Type %v != %v", c.path, ct, c.contentType)
+		}
+		checkNoCache(t, resp)
+
+		if body := w.Body.String(); body != c.expected {
+			t.Errorf("%s: not equal body %q != %q", c.path, body, c.expected)
+		}
+	}
+}
@@ -48,7 +48,18 @@ func sectionLocation(err error, w io.Writer, info *conf.IPInfo) error {
 	err = printF(err, w, "Latitude: %v\n", info.Latitude)
 	err = printF(err, w, "Longitude: %v\n", info.Longitude)
 	err = printF(err, w, "Time zone: %v\n", info.TimeZone)
+	err = printF(err, w, "UTC offset: %v\n", info.UTCOffset)
+	err = printF(err, w, "Time zone abbreviation: %v\n", info.Abbreviation)
+	err = printF(err, w, "DST: %v\n", info.IsDST)
+	err = printF(err, w, "Next DST transition: %v\n", info.NextTransition)
 	err = printF(err, w, "Language: %v\n", info.Language)
 	err = printF(err, w, "Local time: %v\n", info.LocalTime())
-	return printF(err, w, "UTC Time: %v\n", info.UTCTime)
+	err = printF(err, w, "UTC Time: %v\n", info.UTCTime)
+
+	if info.Sources != nil {
+		err = printF(err, w, "Sources: country=%v, city=%v, location=%v\n",
+			info.Sources.Country, info.Sources.City, info.Sources.Location,
+		)
+	}
+	return err
 }
@@ -0,0 +1,103 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// timeFormats are time representations of TimeHandler, the order is used for the full response.
+var timeFormats = []struct { //nolint:gochecknoglobals
+	format func(t time.Time) string
+	name   string
+	title  string
+}{
+	{name: "rfc3339", title: "RFC3339", format: func(t time.Time) string { return t.Format(time.RFC3339) }},
+	{name: "rfc1123", title: "RFC1123", format: func(t time.Time) string { return t.Format(time.RFC1123) }},
+	{name: "unix", title: "Unix", format: func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }},
+	{name: "unixmilli", title: "Unix milli", format: func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }},
+	{name: "isoweek", title: "ISO week", format: isoWeek},
+}
+
+// TimeHandler is handler for text/plain response with time in different formats.
+// By default, it's the request time in the client's time zone, but an instant can be set
+// by "at" parameter (RFC3339 or Unix seconds) and a time zone by "tz" one (IANA name).
+// A single value is returned if "format" parameter is set.
+func TimeHandler(w http.ResponseWriter, r *http.Request, _ *conf.Cfg, info *conf.IPInfo) error {
+	ts, loc, err := requestTime(r, info)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	if format := r.FormValue("format"); format != "" {
+		for _, f := range timeFormats {
+			if f.name == format {
+				return printF(nil, w, "%s\n", f.format(ts))
+			}
+		}
+		return &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("unknown time format %q", format)}
+	}
+
+	err = printF(nil, w, "%-11s %v\n", "Time zone:", loc.String())
+	for _, f := range timeFormats {
+		err = printF(err, w, "%-11s %v\n", f.title+":", f.format(ts))
+	}
+	return err
+}
+
+// requestTime returns time and its location by request parameters or client's info.
+func requestTime(r *http.Request, info *conf.IPInfo) (time.Time, *time.Location, error) {
+	var (
+		ts     = info.Timestamp
+		tzName = info.TimeZone
+	)
+
+	if at := r.FormValue("at"); at != "" {
+		parsed, err := parseInstant(at)
+		if err != nil {
+			return ts, nil, &StatusError{Code: http.StatusBadRequest, Err: err}
+		}
+		ts = parsed
+	}
+
+	if tz := r.FormValue("tz"); tz != "" {
+		tzName = tz
+	}
+
+	loc, err := conf.LoadLocation(tzName)
+	if err != nil {
+		return ts, nil, &StatusError{Code: http.StatusBadRequest, Err: fmt.Errorf("time zone %q: %w", tzName, err)}
+	}
+	return ts.In(loc), loc, nil
+}
+
+// parseInstant parses RFC3339 time or Unix seconds.
+func parseInstant(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ts, errors.New("time should be in RFC3339 format or Unix seconds")
+	}
+	return ts, nil
+}
+
+// isoWeek returns ISO 8601 week date, e.g. 2026-W42-7.
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	weekday := int(t.Weekday())
+
+	if weekday == 0 {
+		weekday = 7 // Sunday
+	}
+	return fmt.Sprintf("%04d-W%02d-%d", year, week, weekday)
+}
@@ -0,0 +1,79 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+func TestTimeHandler(t *testing.T) {
+	info := &conf.IPInfo{
+		TimeZone:  "Europe/Stockholm",
+		Timestamp: time.Date(2019, 7, 14, 3, 4, 5, 6, time.UTC),
+	}
+	cases := []struct {
+		name     string
+		query    string
+		expected string
+		code     int
+	}{
+		{
+			name: "default",
+			expected: "Time zone:  Europe/Stockholm\n" +
+				"RFC3339:    2019-07-14T05:04:05+02:00\n" +
+				"RFC1123:    Sun, 14 Jul 2019 05:04:05 CEST\n" +
+				"Unix:       1563073445\n" +
+				"Unix milli: 1563073445000\n" +
+				"ISO week:   2019-W28-7\n",
+		},
+		{
+			name:  "convert",
+			query: "?at=2019-01-02T03:04:05Z&tz=Asia/Tokyo",
+			expected: "Time zone:  Asia/Tokyo\n" +
+				"RFC3339:    2019-01-02T12:04:05+09:00\n" +
+				"RFC1123:    Wed, 02 Jan 2019 12:04:05 JST\n" +
+				"Unix:       1546398245\n" +
+				"Unix milli: 1546398245000\n" +
+				"ISO week:   2019-W01-3\n",
+		},
+		{name: "unix at", query: "?at=1546398245&format=rfc3339", expected: "2019-01-02T04:04:05+01:00\n"},
+		{name: "unix", query: "?format=unix", expected: "1563073445\n"},
+		{name: "iso week", query: "?format=isoweek&at=2021-01-03T12:00:00Z", expected: "2020-W53-7\n"},
+		{name: "utc", query: "?format=rfc1123&tz=UTC", expected: "Sun, 14 Jul 2019 03:04:05 UTC\n"},
+		{name: "bad format", query: "?format=unknown", code: http.StatusBadRequest},
+		{name: "bad at", query: "?at=yesterday", code: http.StatusBadRequest},
+		{name: "bad tz", query: "?tz=Mars/Olympus", code: http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com/time"+c.query, nil)
+		w := httptest.NewRecorder()
+
+		err := TimeHandler(w, req, nil, info)
+		if c.code != 0 {
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.Code != c.code {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+
+		resp := w.Result()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+		}
+		checkNoCache(t, resp)
+
+		if body := w.Body.String(); body != c.expected {
+			t.Errorf("%s: not equal body %q != %q", c.name, body, c.expected)
+		}
+	}
+}
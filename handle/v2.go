@@ -0,0 +1,276 @@
+package handle
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/geo"
+)
+
+const (
+	// V2Prefix is URL path prefix of API v2.
+	V2Prefix = "/v2"
+	// v2Version is API version of response metadata.
+	v2Version = "2"
+	// requestIDHeader is a header of request ID, it's set by a proxy or generated.
+	requestIDHeader = "X-Request-Id"
+	// maxRequestIDLength is a maximum length of request ID from the request header.
+	maxRequestIDLength = 128
+)
+
+//go:embed v2.schema.json
+var v2Schema []byte
+
+// V2Response is API v2 envelope, it contains Data or Error and always Meta.
+type V2Response struct {
+	Data  *V2Data  `json:"data,omitempty"`
+	Error *V2Error `json:"error,omitempty"`
+	Meta  V2Meta   `json:"meta"`
+}
+
+// V2Data is client's info of API v2, unknown blocks are null.
+type V2Data struct {
+	IP       string      `json:"ip"`
+	Location *V2Location `json:"location"`
+	Network  *V2Network  `json:"network"`
+	Time     *V2Time     `json:"time"`
+	Client   V2Client    `json:"client"`
+}
+
+// V2Place is a named place with ISO code.
+type V2Place struct {
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+// V2Coordinates is a location point, accuracy radius is in kilometers.
+type V2Coordinates struct {
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AccuracyRadius uint16  `json:"accuracy_radius_km,omitempty"`
+}
+
+// V2Location is geo location of API v2, names are in Language.
+type V2Location struct {
+	Country       *V2Place       `json:"country"`
+	Subdivision   *V2Place       `json:"subdivision"`
+	Coordinates   *V2Coordinates `json:"coordinates"`
+	ContinentCode string         `json:"continent_code,omitempty"`
+	City          string         `json:"city,omitempty"`
+	Language      string         `json:"language"`
+}
+
+// V2Network is autonomous system of the client's network.
+type V2Network struct {
+	Organization string `json:"organization,omitempty"`
+	ASN          uint32 `json:"asn"`
+}
+
+// V2Time is time zone details of the client's location.
+type V2Time struct {
+	Zone              string `json:"zone"`
+	Abbreviation      string `json:"abbreviation"`
+	UTCOffset         string `json:"utc_offset"`
+	NextDSTTransition string `json:"next_dst_transition,omitempty"`
+	LocalTime         string `json:"local_time"`
+	UTCTime           string `json:"utc_time"`
+	UTCOffsetSeconds  int    `json:"utc_offset_seconds"`
+	IsDST             bool   `json:"is_dst"`
+}
+
+// V2Client is the client's request details, Type is detected by User-Agent header.
+type V2Client struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	Type      string `json:"type"`
+}
+
+// V2Error is a structured error, Code is HTTP status code and Status is its text.
+type V2Error struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// V2Meta is metadata of API v2 response.
+type V2Meta struct {
+	Sources       *geo.Sources `json:"sources,omitempty"`
+	RequestID     string       `json:"request_id"`
+	APIVersion    string       `json:"api_version"`
+	ServerVersion string       `json:"server_version"`
+	DBBuildDate   string       `json:"db_build_date,omitempty"`
+}
+
+// IsV2Path returns true if URL path without trailing slash belongs to API v2.
+func IsV2Path(path string) bool {
+	return path == V2Prefix || strings.HasPrefix(path, V2Prefix+"/")
+}
+
+// V2Handler returns handler of API v2 paths: client's info and its JSON schema.
+// Errors of this handler should be written by V2ErrorHandler, "fields" parameter is rejected
+// because API v2 data have their own structure.
+func V2Handler(buildInfo *BuildInfo) func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error {
+	return func(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) error {
+		if r.FormValue("fields") != "" {
+			return &StatusError{Code: http.StatusBadRequest, Err: errors.New("fields selection is not supported by API v2")}
+		}
+
+		switch strings.TrimRight(r.URL.Path, "/ ") {
+		case V2Prefix:
+			meta := newV2Meta(w, r, cfg, buildInfo)
+			meta.Sources = info.Sources
+
+			response := &V2Response{Data: newV2Data(r, cfg, info), Meta: meta}
+			if err := writeV2(w, http.StatusOK, response); err != nil {
+				return fmt.Errorf("V2Handler: %w", err)
+			}
+			return nil
+		case V2Prefix + "/schema.json":
+			w.Header().Set("Content-Type", "application/schema+json")
+			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+			if _, err := w.Write(v2Schema); err != nil {
+				return fmt.Errorf("V2Handler: %w", err)
+			}
+			return nil
+		}
+		return &StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("unknown path %q", r.URL.Path)}
+	}
+}
+
+// V2ErrorHandler writes the error as API v2 envelope and returns its status code.
+// Status errors are client ones, so their messages are returned as is, others are logged.
+func V2ErrorHandler(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, buildInfo *BuildInfo, err error) int {
+	var statusErr *StatusError
+	code, message := http.StatusInternalServerError, "internal error"
+
+	if errors.As(err, &statusErr) {
+		code, message = statusErr.Code, statusErr.Error()
+	} else {
+		slog.Error("API v2", "path", r.URL.Path, "error", err)
+	}
+
+	response := &V2Response{
+		Error: &V2Error{Code: code, Status: http.StatusText(code), Message: message},
+		Meta:  newV2Meta(w, r, cfg, buildInfo),
+	}
+
+	if writeErr := writeV2(w, code, response); writeErr != nil {
+		slog.Error("API v2 error response", "error", writeErr)
+	}
+	return code
+}
+
+// newV2Data converts info to API v2 data.
+func newV2Data(r *http.Request, cfg *conf.Cfg, info *conf.IPInfo) *V2Data {
+	data := &V2Data{
+		IP: info.IP,
+		Location: &V2Location{
+			ContinentCode: info.Continent,
+			City:          info.City,
+			Language:      info.Language,
+		},
+		Client: V2Client{UserAgent: r.UserAgent(), Type: cfg.UserAgents.Client(r.UserAgent())},
+	}
+
+	if data.Client.Type == conf.ClientUnknown {
+		data.Client.Type = "unknown"
+	}
+
+	if info.CountryCode != "" {
+		data.Location.Country = &V2Place{Code: info.CountryCode, Name: info.Country}
+	}
+
+	if info.SubdivisionCode != "" {
+		data.Location.Subdivision = &V2Place{Code: info.SubdivisionCode, Name: info.Subdivision}
+	}
+
+	if info.HasCoordinates() {
+		data.Location.Coordinates = &V2Coordinates{
+			Latitude:       info.Latitude,
+			Longitude:      info.Longitude,
+			AccuracyRadius: info.AccuracyRadius,
+		}
+	}
+
+	if info.ASN != 0 {
+		data.Network = &V2Network{ASN: info.ASN, Organization: info.ASOrganization}
+	}
+
+	// time is null if the zone is unknown or can't be loaded
+	if loc, err := conf.LoadLocation(info.TimeZone); info.TimeZone != "" && err == nil {
+		data.Time = &V2Time{
+			Zone:              info.TimeZone,
+			Abbreviation:      info.Abbreviation,
+			UTCOffset:         info.UTCOffset,
+			UTCOffsetSeconds:  info.UTCOffsetSeconds,
+			IsDST:             info.IsDST,
+			NextDSTTransition: info.NextTransition,
+			LocalTime:         info.Timestamp.In(loc).Format(time.RFC3339),
+			UTCTime:           info.UTCTime,
+		}
+	}
+	return data
+}
+
+// newV2Meta returns response metadata, request ID is set to the response header.
+func newV2Meta(w http.ResponseWriter, r *http.Request, cfg *conf.Cfg, buildInfo *BuildInfo) V2Meta {
+	meta := V2Meta{RequestID: requestID(w, r), APIVersion: v2Version}
+
+	if buildInfo != nil {
+		meta.ServerVersion = buildInfo.Version
+	}
+
+	if buildTime := cfg.BuildTime(); !buildTime.IsZero() {
+		meta.DBBuildDate = buildTime.Format(time.DateOnly)
+	}
+	return meta
+}
+
+// requestID returns ID of the response, request header value from a proxy or a new random one.
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		return id
+	}
+
+	id := r.Header.Get(requestIDHeader)
+	if !isValidRequestID(id) {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b) // it never returns an error
+		id = hex.EncodeToString(b)
+	}
+
+	w.Header().Set(requestIDHeader, id)
+	return id
+}
+
+// isValidRequestID returns true if the ID is not empty and contains only safe printable ASCII characters.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeV2 writes JSON response with the status code.
+func writeV2(w http.ResponseWriter, code int, response *V2Response) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(code)
+
+	return json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,344 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z0rr0/ipinfo/conf"
+)
+
+// validateSchema checks the value by a subset of JSON schema keywords which are used by v2Schema.
+func validateSchema(root, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		defs, _ := root["$defs"].(map[string]any)
+		def, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if def == nil {
+			return []string{path + ": unknown reference " + ref}
+		}
+		return validateSchema(root, def, value, path)
+	}
+
+	var errs []string
+	if typ, ok := schema["type"]; ok && !matchSchemaType(typ, value) {
+		return []string{fmt.Sprintf("%s: value %v doesn't match type %v", path, value, typ)}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: value %v is not in %v", path, value, enum))
+	}
+
+	if c, ok := schema["const"]; ok && c != value {
+		errs = append(errs, fmt.Sprintf("%s: value %v is not %v", path, value, c))
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return errs
+	}
+
+	if oneOf, found := schema["oneOf"].([]any); found {
+		matched := 0
+		for _, item := range oneOf {
+			if len(validateSchema(root, item.(map[string]any), value, path)) == 0 {
+				matched++
+			}
+		}
+
+		if matched != 1 {
+			errs = append(errs, fmt.Sprintf("%s: %d of oneOf schemas are matched", path, matched))
+		}
+	}
+
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, found := object[name.(string)]; !found {
+			errs = append(errs, fmt.Sprintf("%s: required %q is missed", path, name))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for name, item := range object {
+		property, found := properties[name].(map[string]any)
+		if !found {
+			if schema["additionalProperties"] == false {
+				errs = append(errs, fmt.Sprintf("%s: unknown property %q", path, name))
+			}
+			continue
+		}
+		errs = append(errs, validateSchema(root, property, item, path+"."+name)...)
+	}
+	return errs
+}
+
+func matchSchemaType(typ, value any) bool {
+	if types, ok := typ.([]any); ok {
+		return slices.ContainsFunc(types, func(t any) bool { return matchSchemaType(t, value) })
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return typ == "null"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == float64(int64(v)))
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	}
+	return false
+}
+
+func TestV2Handler(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal(v2Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newTestCfg(t)
+	handler := V2Handler(&BuildInfo{Version: "v1.2.3"})
+	today := time.Now().UTC().Format(time.DateOnly)
+	hexID := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	cases := []struct {
+		expected  map[string]any // expected values by dot separated paths
+		name      string
+		ip        string
+		path      string
+		userAgent string
+		requestID string
+		code      int
+	}{
+		{
+			name:      "malmo",
+			ip:        "193.138.218.226",
+			path:      "/v2",
+			userAgent: "curl/8.14.1",
+			requestID: "abc-123",
+			code:      http.StatusOK,
+			expected: map[string]any{
+				"data.ip":                                      "193.138.218.226",
+				"data.location.country.code":                   "SE",
+				"data.location.country.name":                   "Sweden",
+				"data.location.subdivision.code":               "SE-M",
+				"data.location.continent_code":                 "EU",
+				"data.location.city":                           "Malmo",
+				"data.location.coordinates.latitude":           55.6078,
+				"data.location.coordinates.longitude":          12.9982,
+				"data.location.coordinates.accuracy_radius_km": float64(20),
+				"data.network.asn":                             float64(39351),
+				"data.network.organization":                    "31173 Services AB",
+				"data.time.zone":                               "Europe/Stockholm",
+				"data.client.type":                             "cli",
+				"data.client.user_agent":                       "curl/8.14.1",
+				"meta.request_id":                              "abc-123",
+				"meta.api_version":                             "2",
+				"meta.server_version":                          "v1.2.3",
+				"meta.db_build_date":                           today,
+			},
+		},
+		{
+			name: "unknown",
+			ip:   "127.0.0.1",
+			path: "/v2/",
+			code: http.StatusOK,
+			expected: map[string]any{
+				"data.ip":                   "127.0.0.1",
+				"data.location.country":     nil,
+				"data.location.coordinates": nil,
+				"data.network":              nil,
+				"data.time":                 nil,
+				"data.client.type":          "unknown",
+				"meta.api_version":          "2",
+			},
+		},
+		{
+			name:      "not found",
+			ip:        "193.138.218.226",
+			path:      "/v2/unknown",
+			requestID: "bad id",
+			code:      http.StatusNotFound,
+			expected: map[string]any{
+				"error.code":       float64(http.StatusNotFound),
+				"error.status":     "Not Found",
+				"error.message":    `unknown path "/v2/unknown"`,
+				"meta.api_version": "2",
+			},
+		},
+		{
+			name: "fields",
+			ip:   "193.138.218.226",
+			path: "/v2?fields=ip,city",
+			code: http.StatusBadRequest,
+			expected: map[string]any{
+				"error.code":    float64(http.StatusBadRequest),
+				"error.message": "fields selection is not supported by API v2",
+			},
+		},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.path, nil)
+		req.Header.Add("X-Real-Ip", c.ip)
+		req.Header.Set("User-Agent", c.userAgent)
+
+		if c.requestID != "" {
+			req.Header.Set("X-Request-Id", c.requestID)
+		}
+
+		info, err := cfg.Info(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		code := http.StatusOK
+
+		if err = handler(w, req, cfg, info); err != nil {
+			code = V2ErrorHandler(w, req, cfg, nil, err)
+		}
+
+		resp := w.Result()
+		if code != c.code || resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code %d %d", c.name, code, resp.StatusCode)
+		}
+
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s: not equal Content-Type: %v", c.name, ct)
+		}
+		checkNoCache(t, resp)
+
+		var response map[string]any
+		if err = json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		for _, e := range validateSchema(schema, schema, response, "$") {
+			t.Errorf("%s: %s", c.name, e)
+		}
+
+		for key, expected := range c.expected {
+			var value any = response
+			for name := range strings.SplitSeq(key, ".") {
+				value = value.(map[string]any)[name]
+			}
+
+			if value != expected {
+				t.Errorf("%s: not equal %s %v != %v", c.name, key, value, expected)
+			}
+		}
+
+		meta := response["meta"].(map[string]any)
+		id := resp.Header.Get("X-Request-Id")
+
+		if meta["request_id"] != id || (c.requestID == "abc-123") != (id == c.requestID) {
+			t.Errorf("%s: unexpected request ID %q %v", c.name, id, meta["request_id"])
+		}
+
+		if id != c.requestID && !hexID.MatchString(id) {
+			t.Errorf("%s: invalid generated request ID %q", c.name, id)
+		}
+	}
+}
+
+func TestNewV2Data_time(t *testing.T) {
+	cfg := newTestCfg(t)
+	req := httptest.NewRequest("GET", "https://example.com/v2", nil)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		zone      string
+		localTime string
+	}{
+		{zone: "Europe/Stockholm", localTime: "2025-01-02T04:04:05+01:00"},
+		{zone: "Mars/Olympus_Mons"},
+		{},
+	}
+	for _, c := range cases {
+		data := newV2Data(req, cfg, &conf.IPInfo{IP: "192.0.2.1", TimeZone: c.zone, Timestamp: now})
+
+		if c.localTime == "" {
+			if data.Time != nil {
+				t.Errorf("%q: time is not null: %+v", c.zone, data.Time)
+			}
+			continue
+		}
+
+		if data.Time == nil || data.Time.LocalTime != c.localTime {
+			t.Errorf("%q: unexpected time %+v", c.zone, data.Time)
+		}
+	}
+}
+
+func TestV2Schema(t *testing.T) {
+	cfg := newTestCfg(t)
+	req := httptest.NewRequest("GET", "https://example.com/v2/schema.json", nil)
+	req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+	info, err := cfg.Info(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err = V2Handler(nil)(w, req, cfg, info); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := w.Result()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/schema+json" {
+		t.Errorf("not equal Content-Type: %v", ct)
+	}
+
+	var schema map[string]any
+	if err = json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := schema["$id"]; id != "https://github.com/z0rr0/ipinfo/handle/v2.schema.json" {
+		t.Errorf("unexpected schema ID %v", id)
+	}
+}
+
+func TestV2ErrorHandler(t *testing.T) {
+	cfg := newTestCfg(t)
+	req := httptest.NewRequest("GET", "https://example.com/v2", nil)
+	w := httptest.NewRecorder()
+
+	if code := V2ErrorHandler(w, req, cfg, &BuildInfo{Version: "v1"}, errors.New("secret details")); code != http.StatusInternalServerError {
+		t.Errorf("unexpected status code %d", code)
+	}
+
+	var response V2Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Error == nil || response.Error.Message != "internal error" || response.Error.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected error %+v", response.Error)
+	}
+
+	if response.Data != nil || response.Meta.ServerVersion != "v1" || response.Meta.RequestID == "" {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestIsV2Path(t *testing.T) {
+	cases := map[string]bool{"/v2": true, "/v2/schema.json": true, "/v2x": false, "": false, "/json": false}
+
+	for path, expected := range cases {
+		if result := IsV2Path(path); result != expected {
+			t.Errorf("%q: not equal %v != %v", path, result, expected)
+		}
+	}
+}
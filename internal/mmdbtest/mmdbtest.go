@@ -0,0 +1,383 @@
+// Copyright 2025 Aleksandr Zaitsev <me@axv.email>.
+// All rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the LICENSE file.
+
+// Package mmdbtest writes synthetic mmdb files and configuration for tests.
+package mmdbtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+const (
+	// CityType is a database type of City format files.
+	CityType = "GeoLite2-City"
+	// DBName is a file name of the fixture database.
+	DBName = "GeoLite2-City.mmdb"
+	// ConfigName is a file name of the fixture configuration.
+	ConfigName = "ipinfo_test.json"
+	// ASNType is a database type of ASN format files.
+	ASNType = "GeoLite2-ASN"
+	// ASNDBName is a file name of the fixture ASN database.
+	ASNDBName = "GeoLite2-ASN.mmdb"
+)
+
+// Network is a database record for a network in CIDR notation.
+// Data values can be strings, bools, numbers (including json.Number), []any and map[string]any.
+type Network struct {
+	Data map[string]any `json:"data"`
+	CIDR string         `json:"cidr"`
+}
+
+// City is a City format record.
+type City struct {
+	Country         map[string]string // localized names
+	City            map[string]string // localized names
+	Subdivision     map[string]string // localized names
+	SubdivisionCode string
+	CIDR            string
+	ISOCode         string
+	Continent       string
+	TimeZone        string
+	Latitude        float64
+	Longitude       float64
+	AccuracyRadius  uint16
+}
+
+// Network returns a network with City format data.
+func (c *City) Network() Network {
+	data := map[string]any{
+		"country": map[string]any{"iso_code": c.ISOCode, "names": names(c.Country)},
+		"location": map[string]any{
+			"latitude":        c.Latitude,
+			"longitude":       c.Longitude,
+			"accuracy_radius": c.AccuracyRadius,
+			"time_zone":       c.TimeZone,
+		},
+	}
+
+	if c.Continent != "" {
+		data["continent"] = map[string]any{"code": c.Continent}
+	}
+
+	if len(c.City) > 0 {
+		data["city"] = map[string]any{"names": names(c.City)}
+	}
+
+	if c.SubdivisionCode != "" {
+		data["subdivisions"] = []any{map[string]any{"iso_code": c.SubdivisionCode, "names": names(c.Subdivision)}}
+	}
+
+	return Network{CIDR: c.CIDR, Data: data}
+}
+
+// Cities are default fixture records.
+var Cities = []City{ //nolint:gochecknoglobals
+	{
+		CIDR:            "193.138.218.0/24",
+		Country:         map[string]string{"en": "Sweden", "de": "Schweden", "ru": "Швеция"},
+		City:            map[string]string{"en": "Malmo", "de": "Malmö", "ru": "Мальмё"},
+		Subdivision:     map[string]string{"en": "Skane County", "ru": "Сконе"},
+		SubdivisionCode: "M",
+		ISOCode:         "SE",
+		Continent:       "EU",
+		TimeZone:        "Europe/Stockholm",
+		Latitude:        55.6078,
+		Longitude:       12.9982,
+		AccuracyRadius:  20,
+	},
+	{
+		CIDR:            "81.2.69.0/24",
+		Country:         map[string]string{"en": "United Kingdom", "ru": "Великобритания"},
+		City:            map[string]string{"en": "London", "ru": "Лондон"},
+		Subdivision:     map[string]string{"en": "England", "ru": "Англия"},
+		SubdivisionCode: "ENG",
+		ISOCode:         "GB",
+		Continent:       "EU",
+		TimeZone:        "Europe/London",
+		Latitude:        51.5142,
+		Longitude:       -0.0931,
+		AccuracyRadius:  10,
+	},
+	{
+		CIDR:           "5.255.255.0/24",
+		Country:        map[string]string{"en": "Russia", "ru": "Россия"},
+		City:           map[string]string{"en": "Moscow", "ru": "Москва"},
+		ISOCode:        "RU",
+		Continent:      "EU",
+		TimeZone:       "Europe/Moscow",
+		Latitude:       55.7527,
+		Longitude:      37.6172,
+		AccuracyRadius: 500,
+	},
+	{
+		CIDR:           "2001:218::/32",
+		Country:        map[string]string{"en": "Japan"},
+		ISOCode:        "JP",
+		Continent:      "AS",
+		TimeZone:       "Asia/Tokyo",
+		Latitude:       35.69,
+		Longitude:      139.69,
+		AccuracyRadius: 100,
+	},
+	{
+		CIDR:           "2a02:d40::/32",
+		Country:        map[string]string{"en": "Sweden", "de": "Schweden", "ru": "Швеция"},
+		City:           map[string]string{"en": "Stockholm", "ru": "Стокгольм"},
+		ISOCode:        "SE",
+		Continent:      "EU",
+		TimeZone:       "Europe/Stockholm",
+		Latitude:       59.3247,
+		Longitude:      18.056,
+		AccuracyRadius: 50,
+	},
+}
+
+// CityNetworks returns networks of cities.
+func CityNetworks(cities []City) []Network {
+	networks := make([]Network, len(cities))
+	for i := range cities {
+		networks[i] = cities[i].Network()
+	}
+	return networks
+}
+
+// ASN is an ASN format record.
+type ASN struct {
+	CIDR         string
+	Organization string
+	Number       uint32
+}
+
+// Network returns a network with ASN format data.
+func (a *ASN) Network() Network {
+	return Network{
+		CIDR: a.CIDR,
+		Data: map[string]any{
+			"autonomous_system_number":       a.Number,
+			"autonomous_system_organization": a.Organization,
+		},
+	}
+}
+
+// ASNs are default fixture ASN records, the last network has no City record.
+var ASNs = []ASN{ //nolint:gochecknoglobals
+	{CIDR: "193.138.218.0/24", Number: 39351, Organization: "31173 Services AB"},
+	{CIDR: "81.2.69.0/24", Number: 20712, Organization: "Andrews & Arnold Ltd"},
+	{CIDR: "2001:218::/32", Number: 2914, Organization: "NTT America, Inc."},
+	{CIDR: "1.1.1.0/24", Number: 13335, Organization: "Cloudflare, Inc."},
+}
+
+// ASNNetworks returns networks of ASN records.
+func ASNNetworks(items []ASN) []Network {
+	networks := make([]Network, len(items))
+	for i := range items {
+		networks[i] = items[i].Network()
+	}
+	return networks
+}
+
+// Write writes a mmdb with IPv4 and IPv6 networks.
+func Write(w io.Writer, databaseType string, networks []Network) error {
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		BuildEpoch:   time.Now().Unix(),
+		DatabaseType: databaseType,
+		Description:  map[string]string{"en": "ipinfo test database"},
+		Languages:    []string{"de", "en", "ru"},
+		// private networks are useful for tests
+		IncludeReservedNetworks: true,
+		IPVersion:               6,
+		RecordSize:              28,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, network := range networks {
+		_, ipNet, parseErr := net.ParseCIDR(network.CIDR)
+		if parseErr != nil {
+			return parseErr
+		}
+
+		value, convertErr := convert("", network.Data)
+		if convertErr != nil {
+			return fmt.Errorf("network %s: %w", network.CIDR, convertErr)
+		}
+
+		if err = tree.Insert(ipNet, value); err != nil {
+			return fmt.Errorf("network %s: %w", network.CIDR, err)
+		}
+	}
+
+	_, err = tree.WriteTo(w)
+	return err
+}
+
+// WriteFile writes a mmdb file.
+func WriteFile(filename, databaseType string, networks []Network) (err error) {
+	f, err := os.Create(filename) //nolint:gosec // test file name
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	return Write(f, databaseType, networks)
+}
+
+// Fixture writes the default City and ASN databases and a configuration for them to the directory.
+// It returns the configuration file path.
+func Fixture(dir string) (string, error) {
+	dbName := filepath.Join(dir, DBName)
+	if err := WriteFile(dbName, CityType, CityNetworks(Cities)); err != nil {
+		return "", fmt.Errorf("write database: %w", err)
+	}
+
+	asnDBName := filepath.Join(dir, ASNDBName)
+	if err := WriteFile(asnDBName, ASNType, ASNNetworks(ASNs)); err != nil {
+		return "", fmt.Errorf("write ASN database: %w", err)
+	}
+
+	data, err := json.MarshalIndent(map[string]any{
+		"host":           "127.0.0.1",
+		"port":           8082,
+		"db":             dbName,
+		"asn_db":         asnDBName,
+		"ignore_headers": []string{"X-Forwarded-For", "X-Forwarded-Proto", "X-Real-Ip", "X-Real-RemoteIp"},
+		"ip_header":      "X-Real-Ip",
+		"cache_size":     128,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	configName := filepath.Join(dir, ConfigName)
+	if err = os.WriteFile(configName, data, 0o600); err != nil {
+		return "", fmt.Errorf("write config: %w", err)
+	}
+	return configName, nil
+}
+
+func names(items map[string]string) map[string]any {
+	result := make(map[string]any, len(items))
+	for k, v := range items {
+		result[k] = v
+	}
+	return result
+}
+
+// convert converts a Go value to mmdb data type, key is a name of the value or its parent slice.
+func convert(key string, value any) (mmdbtype.DataType, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return convertNumber(key, v)
+	case string:
+		return mmdbtype.String(v), nil
+	case bool:
+		return mmdbtype.Bool(v), nil
+	case float64:
+		return mmdbtype.Float64(v), nil
+	case float32:
+		return mmdbtype.Float32(v), nil
+	case int:
+		return mmdbtype.Int32(v), nil //nolint:gosec // test values are small
+	case uint16:
+		return mmdbtype.Uint16(v), nil
+	case uint32:
+		return mmdbtype.Uint32(v), nil
+	case uint64:
+		return mmdbtype.Uint64(v), nil
+	case []any:
+		result := make(mmdbtype.Slice, len(v))
+		for i, item := range v {
+			converted, err := convert(key, item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case map[string]any:
+		result := make(mmdbtype.Map, len(v))
+		for name, item := range v {
+			converted, err := convert(name, item)
+			if err != nil {
+				return nil, err
+			}
+			result[mmdbtype.String(name)] = converted
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", value)
+}
+
+// uint16Keys are names of GeoIP2 fields with uint16 type, other unsigned integers are uint32 ones.
+var uint16Keys = map[string]struct{}{ //nolint:gochecknoglobals
+	"accuracy_radius": {},
+	"confidence":      {},
+	"metro_code":      {},
+}
+
+// doubleKeys are names of GeoIP2 fields with double type, their values can be written as integers.
+var doubleKeys = map[string]struct{}{ //nolint:gochecknoglobals
+	"latitude":        {},
+	"longitude":       {},
+	"static_ip_score": {},
+}
+
+// convertNumber converts JSON number to mmdb data type: integers get GeoIP2 types by the key and others are doubles.
+func convertNumber(key string, number json.Number) (mmdbtype.DataType, error) {
+	_, isDouble := doubleKeys[key]
+
+	i, err := strconv.ParseInt(number.String(), 10, 64)
+	if isDouble || err != nil {
+		f, floatErr := number.Float64()
+		if floatErr != nil {
+			return nil, fmt.Errorf("number %q of %q: %w", number, key, floatErr)
+		}
+		return mmdbtype.Float64(f), nil
+	}
+
+	_, isUint16 := uint16Keys[key]
+	switch {
+	case i < 0:
+		if i < math.MinInt32 {
+			return nil, fmt.Errorf("number %d of %q is out of int32 range", i, key)
+		}
+		return mmdbtype.Int32(i), nil
+	case isUint16:
+		if i > math.MaxUint16 {
+			return nil, fmt.Errorf("number %d of %q is out of uint16 range", i, key)
+		}
+		return mmdbtype.Uint16(i), nil
+	case i > math.MaxUint32:
+		return mmdbtype.Uint64(i), nil
+	}
+	return mmdbtype.Uint32(i), nil
+}
+
+// DecodeNetworks reads JSON array of networks, numbers of data are converted to GeoIP2 types by Write.
+func DecodeNetworks(r io.Reader) ([]Network, error) {
+	var networks []Network
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&networks); err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 Aleksandr Zaitsev <me@axv.email>.
+// All rights reserved. Use of this source code is governed
+// by a BSD-style license that can be found in the LICENSE file.
+
+// Package testenv prepares environment of package tests, it's used only by tests.
+package testenv
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+)
+
+// Run writes fixture files to a temporary directory, changes working directory to it and runs tests.
+// It should be called from TestMain, the configuration is available by relative mmdbtest.ConfigName path.
+func Run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "ipinfo-test-")
+	if err != nil {
+		log.Printf("create temporary directory: %v", err)
+		return 1
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			log.Printf("remove temporary directory: %v", removeErr)
+		}
+	}()
+
+	if err = os.Chdir(dir); err != nil {
+		log.Printf("change directory: %v", err)
+		return 1
+	}
+
+	if _, err = mmdbtest.Fixture(dir); err != nil {
+		log.Printf("write fixture: %v", err)
+		return 1
+	}
+	return m.Run()
+}
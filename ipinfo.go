@@ -78,46 +78,26 @@ func main() {
 		ErrorLog:       loggerInfo,
 	}
 	initLogger(true, os.Stdout)
-	loggerInfo.Printf("\n%v\nlisten addr: %v\n", buildInfo.String(), srv.Addr)
-
-	handlers := map[string]func(http.ResponseWriter, *conf.IPInfo, *handle.BuildInfo) error{
-		"/short":   handle.TextShortHandler,
-		"/compact": handle.TextCompactHandler,
-		"/json":    handle.JSONHandler,
-		"/xml":     handle.XMLHandler,
-		"/html":    handle.HTMLHandler,
-		"/full":    handle.FullHTMLHandler,
-		"/version": handle.VersionHandler,
-	}
+	loggerInfo.Printf("\n%v\nlisten addr: %v\nstorage: %v\n", buildInfo.String(), srv.Addr, cfg.StorageInfo())
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		start, code := time.Now(), http.StatusOK
-		defer func() {
-			loggerInfo.Printf("%-5v %v\t%-12v\t%v",
-				r.Method, code, time.Since(start), r.RemoteAddr,
-			)
-		}()
+	if err = cfg.ReservePaths(isRoute); err != nil {
+		loggerInfo.Fatal(err)
+	}
 
-		info, e := cfg.Info(r)
-		if e != nil {
-			loggerInfo.Println(e)
-			http.Error(w, "ERROR", http.StatusInternalServerError)
-			return
-		}
+	http.HandleFunc("/", rootHandler(cfg, buildInfo))
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
 
-		url := strings.TrimRight(r.URL.Path, "/ ")
-		if h, ok := handlers[url]; ok {
-			e = h(w, info, buildInfo)
-		} else {
-			e = handle.TextHandler(w, r, cfg, info)
+		for range sighup {
+			if e := cfg.Reload(); e != nil {
+				loggerInfo.Printf("config reload error: %v", e)
+				continue
+			}
+			loggerInfo.Println("config reloaded")
 		}
+	}()
 
-		if e != nil {
-			loggerInfo.Println(e)
-			http.Error(w, "ERROR", http.StatusInternalServerError)
-			code = http.StatusInternalServerError
-		}
-	})
 	idleConnsClosed := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
@@ -142,6 +122,131 @@ func main() {
 	loggerInfo.Println("stopped")
 }
 
+// rootHandler returns the main handler, it gets the client's info and calls a handler by URL path.
+func rootHandler(cfg *conf.Cfg, buildInfo *handle.BuildInfo) http.HandlerFunc {
+	handlers, requestHandlers := infoRoutes(), requestRoutes()
+	v2Handler := handle.V2Handler(buildInfo)
+
+	root := handle.GeoFence(cfg, buildInfo, func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) int {
+		var e error
+
+		url := strings.TrimRight(r.URL.Path, "/ ")
+		if handle.IsFieldsRequest(r, url) {
+			e = handle.FieldsHandler(w, r, cfg, info)
+		} else if h, ok := handlers[url]; ok {
+			e = h(w, info, buildInfo)
+		} else if rh, found := requestHandlers[url]; found {
+			e = rh(w, r, cfg, info)
+		} else if handle.IsV2Path(url) {
+			e = v2Handler(w, r, cfg, info)
+		} else if t, exists := cfg.Template(url); exists {
+			e = handle.TemplateHandler(w, t, info)
+		} else if strings.HasPrefix(url, handle.RedirectPrefix) {
+			e = handle.RedirectHandler(w, r, cfg, info)
+		} else {
+			e = handle.NegotiateHandler(w, r, cfg, info)
+		}
+
+		if e == nil {
+			return http.StatusOK
+		}
+
+		if handle.IsV2Path(url) {
+			return handle.V2ErrorHandler(w, r, cfg, buildInfo, e)
+		}
+		return handleError(w, e)
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, code := time.Now(), http.StatusOK
+		defer func() {
+			loggerInfo.Printf("%-5v %v\t%-12v\t%v",
+				r.Method, code, time.Since(start), r.RemoteAddr,
+			)
+		}()
+
+		info, e := cfg.Info(r)
+		if e != nil {
+			if handle.IsV2Path(strings.TrimRight(r.URL.Path, "/ ")) {
+				code = handle.V2ErrorHandler(w, r, cfg, buildInfo, e)
+				return
+			}
+
+			loggerInfo.Println(e)
+			code = http.StatusInternalServerError
+			http.Error(w, "ERROR", code)
+			return
+		}
+
+		code = root(w, r, info)
+	}
+}
+
+// infoRoutes returns handlers of info formats by URL path.
+func infoRoutes() map[string]func(http.ResponseWriter, *conf.IPInfo, *handle.BuildInfo) error {
+	return map[string]func(http.ResponseWriter, *conf.IPInfo, *handle.BuildInfo) error{
+		"/short":        handle.TextShortHandler,
+		"/compact":      handle.TextCompactHandler,
+		"/json":         handle.JSONHandler,
+		"/xml":          handle.XMLHandler,
+		"/yaml":         handle.YAMLHandler,
+		"/csv":          handle.CSVHandler,
+		"/msgpack":      handle.MsgPackHandler,
+		"/cbor":         handle.CBORHandler,
+		"/protobuf":     handle.ProtobufHandler,
+		"/ipinfo.proto": handle.ProtoSchemaHandler,
+		"/shell":        handle.ShellHandler,
+		"/env":          handle.EnvHandler,
+		"/markdown":     handle.MarkdownHandler,
+		"/html":         handle.HTMLHandler,
+		"/full":         handle.FullHTMLHandler,
+		"/version":      handle.VersionHandler,
+		"/openapi.json": handle.OpenAPIHandler,
+		"/ip":           handle.FieldHandler("ip"),
+		"/country":      handle.FieldHandler("country"),
+		"/city":         handle.FieldHandler("city"),
+		"/tz":           handle.FieldHandler("tz"),
+		"/coords":       handle.FieldHandler("coords"),
+	}
+}
+
+// requestRoutes returns handlers which use the request and configuration by URL path.
+func requestRoutes() map[string]func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error {
+	return map[string]func(http.ResponseWriter, *http.Request, *conf.Cfg, *conf.IPInfo) error{
+		"/time":         handle.TimeHandler,
+		"/distance":     handle.DistanceHandler,
+		"/nearest":      handle.NearestHandler,
+		handle.AuthPath: handle.AuthHandler,
+		"/geojson":      handle.GeoJSONHandler,
+		"/kml":          handle.KMLHandler,
+		"/batch":        handle.BatchHandler,
+	}
+}
+
+// isRoute returns true if URL path is handled by built-in handlers, so templates can't use it.
+func isRoute(path string) bool {
+	_, ok := infoRoutes()[path]
+	if !ok {
+		_, ok = requestRoutes()[path]
+	}
+	return ok || handle.IsV2Path(path) || strings.HasPrefix(path, handle.RedirectPrefix)
+}
+
+// handleError writes error response and returns its status code.
+// Status errors are client ones, so their messages are returned as is.
+func handleError(w http.ResponseWriter, err error) int {
+	var statusErr *handle.StatusError
+
+	if errors.As(err, &statusErr) {
+		http.Error(w, statusErr.Error(), statusErr.Code)
+		return statusErr.Code
+	}
+
+	loggerInfo.Println(err)
+	http.Error(w, "ERROR", http.StatusInternalServerError)
+	return http.StatusInternalServerError
+}
+
 // initLogger initializes logger with debug mode and writer.
 func initLogger(debug bool, w io.Writer) {
 	var level = slog.LevelInfo
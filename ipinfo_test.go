@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/z0rr0/ipinfo/conf"
+	"github.com/z0rr0/ipinfo/handle"
+	"github.com/z0rr0/ipinfo/internal/mmdbtest"
+	"github.com/z0rr0/ipinfo/internal/testenv"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testenv.Run(m))
+}
+
+// openAPIDocument is a part of OpenAPI document which is checked by tests.
+type openAPIDocument struct {
+	Paths map[string]struct {
+		Get struct {
+			Responses map[string]struct {
+				Content map[string]json.RawMessage `json:"content"`
+			} `json:"responses"`
+		} `json:"get"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+	Info struct {
+		Version string `json:"version"`
+	} `json:"info"`
+	OpenAPI string `json:"openapi"`
+}
+
+// TestOpenAPI checks that the document describes all routes, their status codes and content types.
+func TestOpenAPI(t *testing.T) {
+	cfg, err := conf.New(mmdbtest.ConfigName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	buildInfo := &handle.BuildInfo{Version: "v1.2.3"}
+	w := httptest.NewRecorder()
+
+	if err = handle.OpenAPIHandler(w, nil, buildInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc openAPIDocument
+	if err = json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info.Version != buildInfo.Version {
+		t.Errorf("unexpected document %q %q", doc.OpenAPI, doc.Info.Version)
+	}
+
+	for _, ref := range strings.Split(w.Body.String(), `"$ref": "#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(ref, `"`)
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("unknown schema reference %q", name)
+		}
+	}
+
+	// routes are all paths of the main handler with a request path for them
+	type route struct {
+		handler func(http.ResponseWriter, *http.Request, *conf.IPInfo) error
+		url     string
+	}
+	routes := map[string]route{
+		"/": {url: "/", handler: func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) error {
+			return handle.NegotiateHandler(w, r, cfg, info)
+		}},
+		handle.RedirectPrefix + "{name}": {url: handle.RedirectPrefix + "unknown", handler: func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) error {
+			return handle.RedirectHandler(w, r, cfg, info)
+		}},
+	}
+
+	for _, path := range []string{handle.V2Prefix, handle.V2Prefix + "/schema.json"} {
+		routes[path] = route{url: path, handler: func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) error {
+			return handle.V2Handler(buildInfo)(w, r, cfg, info)
+		}}
+	}
+
+	for path, h := range infoRoutes() {
+		routes[path] = route{url: path, handler: func(w http.ResponseWriter, _ *http.Request, info *conf.IPInfo) error {
+			return h(w, info, buildInfo)
+		}}
+	}
+
+	for path, h := range requestRoutes() {
+		routes[path] = route{url: path, handler: func(w http.ResponseWriter, r *http.Request, info *conf.IPInfo) error {
+			return h(w, r, cfg, info)
+		}}
+	}
+
+	for path := range doc.Paths {
+		if _, ok := routes[path]; !ok {
+			t.Errorf("path %q is documented but not routed", path)
+		}
+	}
+
+	for path, rt := range routes {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("path %q is routed but not documented", path)
+			continue
+		}
+
+		req := httptest.NewRequest("GET", "https://example.com"+rt.url, nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w = httptest.NewRecorder()
+		code := http.StatusOK
+
+		if err = rt.handler(w, req, info); err != nil {
+			var statusErr *handle.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Errorf("%s: unexpected error: %v", path, err)
+				continue
+			}
+			code = statusErr.Code
+		} else if w.Code != http.StatusOK {
+			code = w.Code
+		}
+
+		response, ok := item.Get.Responses[strconv.Itoa(code)]
+		if !ok {
+			t.Errorf("%s: status code %d is not documented", path, code)
+			continue
+		}
+
+		if err != nil || len(response.Content) == 0 {
+			continue
+		}
+
+		mediaType, _, mediaErr := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if _, found := response.Content[mediaType]; mediaErr != nil || !found {
+			t.Errorf("%s: content type %q is not documented", path, mediaType)
+		}
+	}
+
+	// negotiated media types of the default route
+	negotiated := doc.Paths["/"].Get.Responses["200"].Content
+	for mediaType := range negotiated {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req.Header.Add("X-Real-Ip", "193.138.218.226")
+		req.Header.Set("Accept", mediaType)
+
+		info, infoErr := cfg.Info(req)
+		if infoErr != nil {
+			t.Fatal(infoErr)
+		}
+
+		w = httptest.NewRecorder()
+		if err = handle.NegotiateHandler(w, req, cfg, info); err != nil {
+			t.Errorf("%s: unexpected error: %v", mediaType, err)
+			continue
+		}
+
+		responseType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if _, ok := negotiated[responseType]; !ok {
+			t.Errorf("%s: content type %q is not documented", mediaType, responseType)
+		}
+	}
+
+	if _, ok := negotiated["application/json"]; !ok {
+		t.Error("JSON is not negotiated")
+	}
+}
+
+func TestIsRoute(t *testing.T) {
+	cases := map[string]bool{
+		"/json":           true,
+		"/time":           true,
+		"/v2":             true,
+		"/v2/custom":      true,
+		"/go/docs":        true,
+		"/ip.txt":         false,
+		"/jsonp":          false,
+		"/custom/go/docs": false,
+	}
+
+	for path, expected := range cases {
+		if result := isRoute(path); result != expected {
+			t.Errorf("%q: not equal %v != %v", path, result, expected)
+		}
+	}
+}
+
+func TestRootHandler(t *testing.T) {
+	cfg, err := conf.New(mmdbtest.ConfigName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := cfg.Close(); closeErr != nil {
+			t.Errorf("close error: %v", closeErr)
+		}
+	}()
+
+	handler := rootHandler(cfg, &handle.BuildInfo{Version: "v1.2.3"})
+	cases := []struct {
+		path        string
+		ip          string
+		contentType string
+		code        int
+	}{
+		{path: "/json", ip: "193.138.218.226", code: http.StatusOK, contentType: "application/json; charset=utf-8"},
+		{path: "/v2", ip: "193.138.218.226", code: http.StatusOK, contentType: "application/json; charset=utf-8"},
+		{path: "/json", code: http.StatusInternalServerError, contentType: "text/plain; charset=utf-8"},
+		{path: "/v2", code: http.StatusInternalServerError, contentType: "application/json; charset=utf-8"},
+		{path: "/v2/unknown", ip: "193.138.218.226", code: http.StatusNotFound, contentType: "application/json; charset=utf-8"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "https://example.com"+c.path, nil)
+		if c.ip != "" {
+			req.Header.Add("X-Real-Ip", c.ip)
+		}
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("%s %q: not equal status code %d != %d", c.path, c.ip, w.Code, c.code)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != c.contentType {
+			t.Errorf("%s %q: not equal Content-Type %q != %q", c.path, c.ip, ct, c.contentType)
+		}
+
+		if !handle.IsV2Path(c.path) {
+			continue
+		}
+
+		var response handle.V2Response
+		if err = json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %q: %v", c.path, c.ip, err)
+		}
+
+		if (response.Error == nil) != (c.code == http.StatusOK) || response.Meta.ServerVersion != "v1.2.3" {
+			t.Errorf("%s %q: unexpected response %+v", c.path, c.ip, response)
+		}
+	}
+}